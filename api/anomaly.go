@@ -0,0 +1,144 @@
+package onlinerf
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/kudmo/onlinerf/api/features"
+	"github.com/kudmo/onlinerf/internal/forest"
+	"github.com/kudmo/onlinerf/internal/hst"
+)
+
+// AnomalyConfig configures an AnomalyDetector.
+type AnomalyConfig struct {
+	// NumTrees is the number of half-space trees. Defaults to 25.
+	NumTrees int
+
+	// NumFeatures is the length of the feature vectors. It must be
+	// positive.
+	NumFeatures int
+
+	// Height is the depth of every tree. Defaults to 8.
+	Height int
+
+	// WindowSize is the number of samples per mass-profile window; scores
+	// are based on the last complete window. Defaults to 250.
+	WindowSize int
+
+	// SizeLimit stops scoring at nodes whose reference mass is at most this
+	// value. Defaults to 0.1 * WindowSize.
+	SizeLimit float64
+
+	// Lower and Upper give the expected range of every feature and must
+	// have NumFeatures entries with Lower[i] <= Upper[i]. Defaults to
+	// [0, 1] for every feature. Values outside the range are still handled.
+	Lower []float64
+	Upper []float64
+
+	// Seed determines the random structure of the trees.
+	Seed int64
+}
+
+// AnomalyDetector is an unsupervised streaming anomaly detector built on
+// Half-Space Trees. It learns the density of the stream from unlabeled
+// inputs in tumbling windows and scores inputs by how little mass their
+// region held in the last complete window.
+//
+// An AnomalyDetector is safe for concurrent use from multiple goroutines.
+type AnomalyDetector struct {
+	cfg    AnomalyConfig
+	forest *hst.Forest
+
+	mu sync.RWMutex
+}
+
+// NewAnomalyDetector creates a detector with randomly built half-space
+// trees. It returns an error if NumFeatures is not positive or the feature
+// ranges do not match it.
+func NewAnomalyDetector(cfg AnomalyConfig) (*AnomalyDetector, error) {
+	if cfg.NumFeatures <= 0 {
+		return nil, fmt.Errorf("onlinerf: anomaly detector needs NumFeatures > 0, got %d", cfg.NumFeatures)
+	}
+	if cfg.NumTrees <= 0 {
+		cfg.NumTrees = 25
+	}
+	if cfg.Height <= 0 {
+		cfg.Height = 8
+	}
+	if cfg.WindowSize <= 0 {
+		cfg.WindowSize = 250
+	}
+	if cfg.SizeLimit <= 0 {
+		cfg.SizeLimit = 0.1 * float64(cfg.WindowSize)
+	}
+	if cfg.Lower == nil {
+		cfg.Lower = make([]float64, cfg.NumFeatures)
+	}
+	if cfg.Upper == nil {
+		cfg.Upper = make([]float64, cfg.NumFeatures)
+		for i := range cfg.Upper {
+			cfg.Upper[i] = 1
+		}
+	}
+
+	if len(cfg.Lower) != cfg.NumFeatures || len(cfg.Upper) != cfg.NumFeatures {
+		return nil, fmt.Errorf("onlinerf: anomaly detector ranges have %d and %d entries, want %d",
+			len(cfg.Lower), len(cfg.Upper), cfg.NumFeatures)
+	}
+	for i := range cfg.Lower {
+		if cfg.Lower[i] > cfg.Upper[i] {
+			return nil, fmt.Errorf("onlinerf: anomaly detector range of feature %d is empty: [%g, %g]",
+				i, cfg.Lower[i], cfg.Upper[i])
+		}
+	}
+
+	rng := forest.NewRNG(forest.DeriveSeed(cfg.Seed, 0))
+	return &AnomalyDetector{
+		cfg: cfg,
+		forest: hst.New(hst.Config{
+			NumTrees:    cfg.NumTrees,
+			NumFeatures: cfg.NumFeatures,
+			Height:      cfg.Height,
+			WindowSize:  cfg.WindowSize,
+			SizeLimit:   cfg.SizeLimit,
+			Lower:       cfg.Lower,
+			Upper:       cfg.Upper,
+		}, rng),
+	}, nil
+}
+
+// Score returns the anomaly score of fv in [0,1]; higher is more anomalous.
+// Inputs in regions at least as dense as a uniform distribution score 0.
+// Before the first window is complete there is no reference and Score
+// returns 0 (see Ready).
+func (a *AnomalyDetector) Score(fv features.FeatureVector) float64 {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if !a.forest.Ready() {
+		return 0
+	}
+	return a.forest.Score(fv)
+}
+
+// Mass returns the raw mass score of fv relative to the window size,
+// averaged over trees. Lower values are more anomalous.
+func (a *AnomalyDetector) Mass(fv features.FeatureVector) float64 {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.forest.Mass(fv)
+}
+
+// Update adds fv to the current window.
+func (a *AnomalyDetector) Update(fv features.FeatureVector) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.forest.Update(fv)
+}
+
+// Ready reports whether a complete reference window is available.
+func (a *AnomalyDetector) Ready() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.forest.Ready()
+}
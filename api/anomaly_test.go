@@ -0,0 +1,77 @@
+package onlinerf
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+// TestAnomalyDetectorScoresOutliers проверяет, что точки вне плотного
+// кластера получают более высокий балл аномальности.
+func TestAnomalyDetectorScoresOutliers(t *testing.T) {
+	a, err := NewAnomalyDetector(AnomalyConfig{NumFeatures: 2, Seed: 6})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a.Ready() || a.Score(features.FeatureVector{0.5, 0.5}) != 0 {
+		t.Fatalf("expected no scores before the first window")
+	}
+
+	rng := rand.New(rand.NewSource(53))
+	for i := 0; i < 1000; i++ {
+		a.Update(features.FeatureVector{0.3 + 0.1*rng.NormFloat64(), 0.6 + 0.1*rng.NormFloat64()})
+	}
+	if !a.Ready() {
+		t.Fatalf("expected a reference window after 1000 samples")
+	}
+
+	normal := a.Score(features.FeatureVector{0.3, 0.6})
+	outlier := a.Score(features.FeatureVector{0.95, 0.05})
+	if normal > 0.2 || outlier < 0.8 {
+		t.Fatalf("expected low score for normal and high for outlier, got %v and %v", normal, outlier)
+	}
+	if a.Mass(features.FeatureVector{0.3, 0.6}) <= a.Mass(features.FeatureVector{0.95, 0.05}) {
+		t.Fatalf("expected the cluster to hold more mass than the outlier")
+	}
+}
+
+// TestAnomalyDetectorAdaptsToShift проверяет, что после сдвига потока
+// новая область перестаёт считаться аномальной.
+func TestAnomalyDetectorAdaptsToShift(t *testing.T) {
+	a, err := NewAnomalyDetector(AnomalyConfig{NumFeatures: 2, WindowSize: 200, Seed: 7})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rng := rand.New(rand.NewSource(54))
+	sample := func(cx, cy float64) features.FeatureVector {
+		return features.FeatureVector{cx + 0.05*rng.NormFloat64(), cy + 0.05*rng.NormFloat64()}
+	}
+
+	for i := 0; i < 600; i++ {
+		a.Update(sample(0.2, 0.2))
+	}
+	before := a.Score(features.FeatureVector{0.8, 0.8})
+	for i := 0; i < 600; i++ {
+		a.Update(sample(0.8, 0.8))
+	}
+	after := a.Score(features.FeatureVector{0.8, 0.8})
+	if before < 0.8 || after > 0.2 {
+		t.Fatalf("expected the new region to become normal, before=%v after=%v", before, after)
+	}
+}
+
+// TestAnomalyDetectorRejectsInvalidConfig проверяет ошибки для конфигураций,
+// с которыми деревья не могут быть построены.
+func TestAnomalyDetectorRejectsInvalidConfig(t *testing.T) {
+	for name, cfg := range map[string]AnomalyConfig{
+		"no features":    {},
+		"short lower":    {NumFeatures: 2, Lower: []float64{0}},
+		"short upper":    {NumFeatures: 2, Upper: []float64{1}},
+		"inverted range": {NumFeatures: 1, Lower: []float64{1}, Upper: []float64{0}},
+	} {
+		if a, err := NewAnomalyDetector(cfg); err == nil || a != nil {
+			t.Fatalf("%s: expected an error, got %v", name, err)
+		}
+	}
+}
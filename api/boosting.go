@@ -0,0 +1,148 @@
+package onlinerf
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/kudmo/onlinerf/api/features"
+	"github.com/kudmo/onlinerf/internal/forest"
+)
+
+// BoostingConfig configures a BoostingPredictor.
+type BoostingConfig struct {
+	// NumTrees is the number of boosted trees. Defaults to 10.
+	NumTrees int
+
+	// NumFeatures is the length of the feature vectors passed to Predict
+	// and Update. It must be positive.
+	NumFeatures int
+
+	// TreeOptions configures the boosted trees exactly like the trees of a
+	// Predictor.
+	TreeOptions
+
+	// Seed derives the per-tree random streams used for Poisson sampling.
+	Seed int64
+}
+
+// BoostingPredictor is an online boosting ensemble (OzaBoost, Oza & Russell
+// 2001) of Hoeffding trees. Every sample passes through the trees in order;
+// its weight λ starts at 1 and is raised for the next tree when the current
+// tree misclassifies it and lowered when it is classified correctly, so
+// later trees focus on samples earlier trees get wrong. Each tree is trained
+// with Poisson(λ) copies of the sample.
+//
+// Predictions average the trees' probabilities weighted by
+// log((1-ε)/ε), where ε is a tree's weighted training error; trees with
+// ε >= 0.5 are ignored.
+//
+// A BoostingPredictor is safe for concurrent use from multiple goroutines.
+type BoostingPredictor struct {
+	cfg BoostingConfig
+
+	trees []*forest.Tree
+	// correct and wrong are the sums of λ for samples each tree classified
+	// correctly and incorrectly (λ^sc and λ^sw in the paper).
+	correct []float64
+	wrong   []float64
+	samples float64
+
+	mu sync.RWMutex
+}
+
+// NewBoostingPredictor creates an empty boosting ensemble. It returns an
+// error if NumFeatures is not positive.
+func NewBoostingPredictor(cfg BoostingConfig) (*BoostingPredictor, error) {
+	if cfg.NumFeatures <= 0 {
+		return nil, fmt.Errorf("onlinerf: boosting needs NumFeatures > 0, got %d", cfg.NumFeatures)
+	}
+	if cfg.NumTrees <= 0 {
+		cfg.NumTrees = 10
+	}
+
+	treeCfg := newTreeConfig(cfg.TreeOptions)
+
+	b := &BoostingPredictor{
+		cfg:     cfg,
+		trees:   make([]*forest.Tree, cfg.NumTrees),
+		correct: make([]float64, cfg.NumTrees),
+		wrong:   make([]float64, cfg.NumTrees),
+	}
+	for i := range b.trees {
+		treeCfg.Seed = forest.DeriveSeed(cfg.Seed, i)
+		b.trees[i] = forest.NewTree(treeCfg, cfg.NumFeatures)
+	}
+	return b, nil
+}
+
+// Predict returns the boosted estimate of the positive class probability.
+func (b *BoostingPredictor) Predict(fv features.FeatureVector) float64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var sum, norm float64
+	for i, t := range b.trees {
+		w := b.treeWeight(i)
+		if w <= 0 {
+			continue
+		}
+		sum += w * t.Predict(fv)
+		norm += w
+	}
+	if norm == 0 {
+		// No tree is better than chance yet; fall back to the first tree,
+		// which is trained on the unweighted stream.
+		return b.trees[0].Predict(fv)
+	}
+	return sum / norm
+}
+
+// treeWeight returns the voting weight log((1-ε)/ε) of tree i. Callers must
+// hold b.mu.
+func (b *BoostingPredictor) treeWeight(i int) float64 {
+	total := b.correct[i] + b.wrong[i]
+	if total == 0 {
+		return 0
+	}
+	// Bound ε away from zero so a perfect tree gets a large, finite weight.
+	eps := math.Max(b.wrong[i]/total, 1e-6)
+	if eps >= 0.5 {
+		return 0
+	}
+	return math.Log((1 - eps) / eps)
+}
+
+// Update trains the ensemble with a single labeled sample.
+func (b *BoostingPredictor) Update(fv features.FeatureVector, label bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.samples++
+	lambda := 1.0
+	for i, t := range b.trees {
+		t.UpdateWeighted(fv, label, float64(t.Rand.Poisson(lambda)))
+
+		if (t.Predict(fv) >= 0.5) == label {
+			b.correct[i] += lambda
+			lambda *= b.samples / (2 * b.correct[i])
+		} else {
+			b.wrong[i] += lambda
+			lambda *= b.samples / (2 * b.wrong[i])
+		}
+	}
+}
+
+// TreeErrors returns the weighted training error ε of every tree.
+func (b *BoostingPredictor) TreeErrors() []float64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	errs := make([]float64, len(b.trees))
+	for i := range b.trees {
+		if total := b.correct[i] + b.wrong[i]; total > 0 {
+			errs[i] = b.wrong[i] / total
+		}
+	}
+	return errs
+}
@@ -0,0 +1,99 @@
+package onlinerf
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+func newTestBooster(t *testing.T, seed int64) *BoostingPredictor {
+	t.Helper()
+	b, err := NewBoostingPredictor(BoostingConfig{
+		NumTrees:    5,
+		NumFeatures: 2,
+		TreeOptions: TreeOptions{
+			MaxDepth:            4,
+			MinSamplesPerLeaf:   20,
+			HoeffdingSplitDelta: 0.01,
+			GracePeriod:         20,
+		},
+		Seed: seed,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return b
+}
+
+// TestBoostingLearnsDiagonalConcept проверяет, что бустинг обучается на
+// диагональной границе и взвешивает деревья по их ошибке.
+func TestBoostingLearnsDiagonalConcept(t *testing.T) {
+	b := newTestBooster(t, 1)
+	rng := rand.New(rand.NewSource(44))
+	label := func(fv features.FeatureVector) bool { return fv[0]+fv[1] > 1 }
+
+	// Деревья берут пороги кандидатов из первого примера, на котором
+	// обучаются, поэтому начинаем с центра области.
+	b.Update(features.FeatureVector{0.5, 0.5}, false)
+	seeded := 0
+	for _, tree := range b.trees {
+		if tree.Root == nil {
+			continue
+		}
+		seeded++
+		for i, fs := range tree.Root.FeatureStats {
+			if fs.Threshold != 0.5 {
+				t.Fatalf("expected root threshold 0.5 for feature %d, got %v", i, fs.Threshold)
+			}
+		}
+	}
+	if seeded == 0 {
+		t.Fatalf("expected the first sample to seed at least one tree")
+	}
+
+	for i := 0; i < 5000; i++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64()}
+		b.Update(fv, label(fv))
+	}
+
+	correct := 0
+	for i := 0; i < 1000; i++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64()}
+		if (b.Predict(fv) >= 0.5) == label(fv) {
+			correct++
+		}
+	}
+	if acc := float64(correct) / 1000; acc < 0.75 {
+		t.Fatalf("expected accuracy above 0.75, got %v", acc)
+	}
+
+	errs := b.TreeErrors()
+	if len(errs) != 5 || errs[0] <= 0 || errs[0] >= 0.5 {
+		t.Fatalf("unexpected tree errors %v", errs)
+	}
+}
+
+// TestBoostingReproducible проверяет, что одинаковый seed и порядок
+// примеров дают одинаковые предсказания.
+func TestBoostingReproducible(t *testing.T) {
+	a, b := newTestBooster(t, 7), newTestBooster(t, 7)
+	rng := rand.New(rand.NewSource(45))
+	for i := 0; i < 2000; i++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64()}
+		a.Update(fv, fv[0] > 0.3)
+		b.Update(fv, fv[0] > 0.3)
+	}
+	fv := features.FeatureVector{0.35, 0.8}
+	if a.Predict(fv) != b.Predict(fv) {
+		t.Fatalf("expected identical predictions, got %v and %v", a.Predict(fv), b.Predict(fv))
+	}
+}
+
+// TestBoostingRejectsInvalidConfig проверяет, что бустинг без признаков
+// не создаётся.
+func TestBoostingRejectsInvalidConfig(t *testing.T) {
+	if b, err := NewBoostingPredictor(BoostingConfig{NumTrees: 3}); err == nil || b != nil {
+		t.Fatalf("expected an error for missing NumFeatures")
+	}
+}
@@ -0,0 +1,163 @@
+package onlinerf
+
+import (
+	"github.com/kudmo/onlinerf/api/features"
+	"github.com/kudmo/onlinerf/internal/calibration"
+)
+
+// CalibrationMethod selects the online calibration layer of a Predictor.
+type CalibrationMethod int
+
+const (
+	// CalibrationNone disables calibration; PredictCalibrated returns the
+	// raw forest score.
+	CalibrationNone CalibrationMethod = iota
+	// CalibrationPlatt fits online Platt scaling (a logistic regression on
+	// the logit of the forest score).
+	CalibrationPlatt
+	// CalibrationIsotonic fits an isotonic regression over a sliding
+	// window of recent scores and labels.
+	CalibrationIsotonic
+)
+
+// CalibrationConfig configures the optional calibration layer. Calibrators
+// are trained prequentially: in Update the forest scores the sample before
+// learning from it, and that score is used to train the calibrator.
+type CalibrationConfig struct {
+	// Method selects the calibrator.
+	Method CalibrationMethod
+
+	// LearningRate is the base SGD step size for Platt scaling.
+	// Defaults to 0.05.
+	LearningRate float64
+
+	// Window is the number of recent samples used by isotonic regression.
+	// Defaults to 1000.
+	Window int
+
+	// RefitInterval is the number of updates between isotonic refits.
+	// Defaults to 50.
+	RefitInterval int
+
+	// Bins is the number of equally wide bins in reliability reports.
+	// Defaults to 10.
+	Bins int
+}
+
+// ReliabilityBin is one bucket of a reliability diagram.
+type ReliabilityBin struct {
+	// Lower and Upper bound the predicted probabilities in the bin.
+	Lower float64
+	Upper float64
+	// Count is the number of predictions that fell into the bin.
+	Count int
+	// MeanPredicted is the average predicted probability in the bin.
+	MeanPredicted float64
+	// ObservedRate is the fraction of positive labels in the bin.
+	ObservedRate float64
+}
+
+// ReliabilityReport summarizes how well predicted probabilities match the
+// observed frequencies of the positive class.
+type ReliabilityReport struct {
+	Bins []ReliabilityBin
+	// ECE is the expected calibration error: the count-weighted mean of
+	// |MeanPredicted - ObservedRate| over all bins.
+	ECE float64
+}
+
+func newCalibrator(cfg CalibrationConfig) calibration.Calibrator {
+	switch cfg.Method {
+	case CalibrationPlatt:
+		lr := cfg.LearningRate
+		if lr <= 0 {
+			lr = 0.05
+		}
+		return calibration.NewPlatt(lr)
+	case CalibrationIsotonic:
+		window := cfg.Window
+		if window <= 0 {
+			window = 1000
+		}
+		refit := cfg.RefitInterval
+		if refit <= 0 {
+			refit = 50
+		}
+		return calibration.NewIsotonic(window, refit)
+	default:
+		return nil
+	}
+}
+
+func newReliability(cfg CalibrationConfig) *calibration.Reliability {
+	bins := cfg.Bins
+	if bins <= 0 {
+		bins = 10
+	}
+	return calibration.NewReliability(bins)
+}
+
+// PredictCalibrated returns the calibrated probability of the positive class
+// for fv. Without a configured calibration method it is equal to Predict.
+// Like Predict, it feeds the feature drift monitor when
+// FeatureDrift.MonitorPredict is set.
+func (p *Predictor) PredictCalibrated(fv features.FeatureVector) float64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	score := p.predictLocked(p.scoringInput(fv))
+	if p.calibrator == nil {
+		return score
+	}
+	return p.calibrator.Calibrate(score)
+}
+
+// Reliability returns reliability-diagram reports for the calibrated and the
+// raw forest probabilities, collected prequentially during Update. Both are
+// empty if no calibration method is configured.
+func (p *Predictor) Reliability() (calibrated, raw ReliabilityReport) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.calibrator == nil {
+		return ReliabilityReport{}, ReliabilityReport{}
+	}
+	return reliabilityReport(p.calReliability), reliabilityReport(p.rawReliability)
+}
+
+func reliabilityReport(r *calibration.Reliability) ReliabilityReport {
+	width := 1.0 / float64(len(r.Bins))
+	report := ReliabilityReport{Bins: make([]ReliabilityBin, len(r.Bins))}
+
+	total := 0.0
+	for i, b := range r.Bins {
+		bin := ReliabilityBin{
+			Lower: float64(i) * width,
+			Upper: float64(i+1) * width,
+			Count: int(b.Count),
+		}
+		if b.Count > 0 {
+			bin.MeanPredicted = b.SumProb / b.Count
+			bin.ObservedRate = b.SumLabel / b.Count
+			gap := bin.MeanPredicted - bin.ObservedRate
+			if gap < 0 {
+				gap = -gap
+			}
+			report.ECE += b.Count * gap
+			total += b.Count
+		}
+		report.Bins[i] = bin
+	}
+	if total > 0 {
+		report.ECE /= total
+	}
+	return report
+}
+
+// updateCalibration trains the calibrator with the prequential forest score
+// of a sample. Callers must hold p.mu.
+func (p *Predictor) updateCalibration(score float64, label bool) {
+	p.rawReliability.Add(score, label)
+	p.calReliability.Add(p.calibrator.Calibrate(score), label)
+	p.calibrator.Update(score, label)
+}
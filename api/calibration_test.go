@@ -0,0 +1,68 @@
+package onlinerf
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+// TestCalibrationFixesWeightedScores проверяет, что калибровка исправляет
+// смещённые вероятности: при взвешивании классов лес выдаёт ~0.5, хотя
+// доля положительных примеров около 10%.
+func TestCalibrationFixesWeightedScores(t *testing.T) {
+	for name, method := range map[string]CalibrationMethod{
+		"platt":    CalibrationPlatt,
+		"isotonic": CalibrationIsotonic,
+	} {
+		pred := mustPredictor(PredictorConfig{
+			NumTrees:    2,
+			NumFeatures: 1,
+			TreeOptions: TreeOptions{
+				MaxDepth:          3,
+				MinSamplesPerLeaf: 1 << 30,
+			},
+			Imbalance:   ImbalanceConfig{Strategy: ImbalanceClassWeight},
+			Calibration: CalibrationConfig{Method: method, LearningRate: 0.1},
+		})
+
+		rng := rand.New(rand.NewSource(18))
+		for i := 0; i < 5000; i++ {
+			pred.Update(features.FeatureVector{rng.Float64()}, rng.Float64() < 0.1)
+		}
+
+		fv := features.FeatureVector{0.5}
+		raw := pred.Predict(fv)
+		cal := pred.PredictCalibrated(fv)
+		if math.Abs(raw-0.5) > 0.05 {
+			t.Fatalf("%s: expected weighted raw score near 0.5, got %v", name, raw)
+		}
+		if math.Abs(cal-0.1) > 0.03 {
+			t.Fatalf("%s: expected calibrated probability near 0.1, got %v", name, cal)
+		}
+
+		calRep, rawRep := pred.Reliability()
+		if len(calRep.Bins) != 10 {
+			t.Fatalf("%s: expected 10 reliability bins, got %d", name, len(calRep.Bins))
+		}
+		if calRep.ECE >= rawRep.ECE {
+			t.Fatalf("%s: expected calibration to reduce ECE, calibrated=%v raw=%v", name, calRep.ECE, rawRep.ECE)
+		}
+	}
+}
+
+// TestPredictCalibratedWithoutCalibration проверяет, что без калибровки
+// PredictCalibrated совпадает с Predict.
+func TestPredictCalibratedWithoutCalibration(t *testing.T) {
+	pred := mustPredictor(PredictorConfig{NumTrees: 2, NumFeatures: 3, TreeOptions: TreeOptions{MaxDepth: 4, MinSamplesPerLeaf: 10}})
+	trainSynthetic(pred, 500, 19)
+
+	fv := features.FeatureVector{0.2, 0.4, 0.6}
+	if pred.PredictCalibrated(fv) != pred.Predict(fv) {
+		t.Fatalf("expected PredictCalibrated to equal Predict without calibration")
+	}
+	if rep, _ := pred.Reliability(); len(rep.Bins) != 0 {
+		t.Fatalf("expected empty reliability report without calibration")
+	}
+}
@@ -3,27 +3,70 @@
 // simple feature pipeline.
 package onlinerf
 
-import "github.com/kudmo/onlinerf/api/features"
+import (
+	"github.com/kudmo/onlinerf/api/features"
+	"github.com/kudmo/onlinerf/internal/forest"
+)
 
-// PredictorConfig controls the online random forest model and the feature
-// processing pipeline used by a Predictor.
-//
-// Most users will:
-//   - choose the number of trees and maximum depth / node budget
-//   - decide whether to enable concept-drift detection
-//   - configure the feature pipeline via FeatureConfig / NormalizerConfig
-//
-// All fields are exported so that configurations can be serialized if needed.
-type PredictorConfig struct {
-	// NumTrees is the size of the forest (number of Hoeffding trees).
-	// Larger values increase accuracy at the cost of memory and CPU.
-	NumTrees            int
+// LeafPrediction selects how tree leaves turn their statistics into a
+// probability of the positive class.
+type LeafPrediction = forest.LeafPrediction
 
-	// NumFeatures is the dimensionality of the embedded feature vectors
-	// that will be passed into the forest. This must match the length of
-	// the FeatureVector values you pass to Predictor.Update / Predictor.Predict.
-	NumFeatures         int
+const (
+	// LeafMajorityClass predicts the frequency of the positive class
+	// observed at the leaf. This is the default.
+	LeafMajorityClass = forest.LeafMajorityClass
+	// LeafNaiveBayes predicts with a Gaussian naive Bayes model over the
+	// leaf's per-feature statistics, which is more accurate for young
+	// leaves that have seen few samples.
+	LeafNaiveBayes = forest.LeafNaiveBayes
+	// LeafNBAdaptive chooses per leaf whichever of majority class and
+	// naive Bayes has been more accurate on the samples seen by the leaf.
+	LeafNBAdaptive = forest.LeafNBAdaptive
+	// LeafLogistic predicts with an online logistic regression trained at
+	// each leaf (a model tree), which gives smoother probabilities and
+	// better accuracy for small trees. New leaves start from their
+	// parent's model.
+	LeafLogistic = forest.LeafLogistic
+)
 
+// SplitMode selects how trees decide on and revisit splits.
+type SplitMode = forest.SplitMode
+
+const (
+	// SplitVFDT splits a leaf once its best candidate beats the runner-up
+	// by the Hoeffding bound; splits are permanent. This is the default.
+	SplitVFDT = forest.SplitVFDT
+	// SplitEFDT (Extremely Fast Decision Tree) splits as soon as the best
+	// candidate beats not splitting, and later replaces a split, regrowing
+	// its subtree, when another feature becomes significantly better.
+	SplitEFDT = forest.SplitEFDT
+)
+
+// ObliqueFeature is the split feature reported for oblique splits, which
+// test a linear combination of features instead of a single one.
+const ObliqueFeature = forest.ObliqueFeature
+
+// SplitCriterion scores candidate splits and supplies the range of its merit
+// for the Hoeffding bound. Custom criteria can implement this interface.
+type SplitCriterion = forest.SplitCriterion
+
+// ClassCounts holds per-class sample counts passed to a SplitCriterion.
+type ClassCounts = forest.ClassCounts
+
+type (
+	// GiniCriterion uses the decrease in Gini impurity (R = 1).
+	GiniCriterion = forest.GiniCriterion
+	// InfoGainCriterion uses information gain (R = log2(classes)).
+	InfoGainCriterion = forest.InfoGainCriterion
+	// HellingerCriterion uses the Hellinger distance (R = sqrt(2)), which
+	// is insensitive to class skew.
+	HellingerCriterion = forest.HellingerCriterion
+)
+
+// TreeOptions configures the individual Hoeffding trees of an ensemble. It
+// is shared by PredictorConfig and BoostingConfig.
+type TreeOptions struct {
 	// MaxDepth is the maximum depth allowed for each tree. Limiting depth
 	// bounds memory usage and acts as a regularizer.
 	MaxDepth            int
@@ -42,23 +85,157 @@ type PredictorConfig struct {
 	MinSamplesPerLeaf   int
 
 	// UseDriftDetection enables concept-drift detection at the leaf level
-	// using an ADWIN-like detector. When drift is detected, the leaf is
-	// reset to forget stale statistics.
+	// using an ADWIN-like detector over the leaf's last 1000 labels. When
+	// drift is detected, the leaf is reset to forget stale statistics.
 	UseDriftDetection   bool
 
 	// DriftAlpha is the significance level controlling the sensitivity of
 	// the drift detector. Smaller values make it harder to trigger drift.
+	// Defaults to 0.002.
 	DriftAlpha          float64
 
+	// LeafPrediction selects the leaf prediction strategy. Defaults to
+	// LeafMajorityClass.
+	LeafPrediction LeafPrediction
+
+	// LeafLearningRate is the SGD step size of LeafLogistic leaf models.
+	// Defaults to 0.1.
+	LeafLearningRate float64
+
+	// SplitCriterion selects the split quality measure. Defaults to
+	// GiniCriterion.
+	SplitCriterion SplitCriterion
+
+	// TieThreshold is the VFDT tie-breaking threshold (tau). When two
+	// candidate features are nearly equally good, the leaf splits on the
+	// best one as soon as the Hoeffding bound falls below TieThreshold
+	// instead of waiting indefinitely. Zero disables tie breaking; 0.05 is
+	// a common choice.
+	TieThreshold float64
+
+	// GracePeriod is the number of samples a leaf must receive between
+	// split attempts. Larger values reduce the CPU cost of Update at the
+	// price of slightly delayed splits. Zero evaluates on every sample.
+	GracePeriod int
+
+	// PrePrune enables the standard null-split comparison: a leaf splits
+	// only if its best candidate also beats not splitting (merit 0) by the
+	// Hoeffding bound. This stops trees from growing meaningless nodes on
+	// noisy streams.
+	PrePrune bool
+
+	// MinSplitGain is the minimum merit (as measured by SplitCriterion)
+	// that the best candidate must reach before a leaf may split.
+	MinSplitGain float64
+
+	// FadingFactor, if in (0, 1), exponentially decays leaf statistics by
+	// this factor per sample seen by the tree, giving leaves a soft sliding
+	// window of roughly 1/(1-FadingFactor) samples. Zero or one disables
+	// decay.
+	FadingFactor float64
+
+	// SplitMode selects VFDT (default) or EFDT split decisions.
+	SplitMode SplitMode
+
+	// ReevaluationPeriod is the number of samples an internal node receives
+	// between re-evaluations of its split in SplitEFDT mode. Zero uses
+	// GracePeriod.
+	ReevaluationPeriod int
+
+	// ObliqueSplits lets each leaf train a small online logistic model and
+	// split on its output (w·x + b <= 0) when that beats all axis-aligned
+	// candidates under the Hoeffding bound. This captures diagonal
+	// boundaries such as cpu+mem > 0.7 with a single split.
+	ObliqueSplits bool
+
+	// ObliqueFeatures is the number of random features per oblique
+	// candidate. Zero uses all features.
+	ObliqueFeatures int
+
+	// ObliqueLearningRate is the SGD step size of oblique candidates.
+	// Defaults to 0.1.
+	ObliqueLearningRate float64
+}
+
+// PredictorConfig controls the online random forest model and the feature
+// processing pipeline used by a Predictor.
+//
+// Most users will:
+//   - choose the number of trees and maximum depth / node budget
+//   - decide whether to enable concept-drift detection
+//   - configure the feature pipeline via FeatureConfig / NormalizerConfig
+//
+// All fields are exported so that configurations can be serialized if needed.
+type PredictorConfig struct {
+	// NumTrees is the size of the forest (number of Hoeffding trees).
+	// Larger values increase accuracy at the cost of memory and CPU.
+	NumTrees            int
+
+	// NumFeatures is the dimensionality of the embedded feature vectors
+	// that will be passed into the forest. This must match the length of
+	// the FeatureVector values you pass to Predictor.Update / Predictor.Predict.
+	NumFeatures         int
+
+	// TreeOptions configures the Hoeffding trees of the forest.
+	TreeOptions
+
+	// Imbalance configures class-imbalance handling (class weighting or
+	// over/under-bagging), the decision threshold used by Classify and
+	// prequential metrics for monitoring minority-class recall.
+	Imbalance ImbalanceConfig
+
+	// Calibration configures an optional online calibration layer (Platt
+	// scaling or windowed isotonic regression) used by PredictCalibrated.
+	Calibration CalibrationConfig
+
+	// Replacement configures forest-level drift handling: each tree's
+	// windowed error is tracked and trees that become significantly worse
+	// than the ensemble are replaced by fresh ones.
+	Replacement ReplacementConfig
+
+	// Ensemble selects how trees are trained: plain online bagging
+	// (default) or Leveraging Bagging.
+	Ensemble EnsembleMode
+
+	// Leveraging configures EnsembleLeveraging.
+	Leveraging LeveragingConfig
+
+	// RandomPatches configures EnsembleRandomPatches.
+	RandomPatches RandomPatchesConfig
+
+	// FeatureDrift configures input (covariate) drift monitoring, which
+	// tracks per-feature distributions independently of labels.
+	FeatureDrift FeatureDriftConfig
+
+	// Pending configures the buffer of predictions awaiting delayed labels
+	// (see PredictWithID and Feedback).
+	Pending PendingConfig
+
+	// Seed is the base seed for all randomness in the forest. Every tree
+	// gets its own independent stream derived from Seed and its index, so
+	// an identical Seed and sample order produce bit-identical trees and
+	// predictions.
+	Seed int64
+
 	// FeatureConfig configures how raw numeric / categorical inputs are
 	// mapped into an embedded FeatureVector by an Embedder.
 	FeatureConfig features.FeatureConfig
 
 	// EmbedderFactory optionally allows plugging in a custom feature
 	// embedding implementation. If nil, IdentityEmbedder is used.
+	// Built-in factories include HashingEmbedderFactory and
+	// OneHotEmbedderFactory from the features package.
 	EmbedderFactory  features.EmbedderFactory
 
 	// NormalizerConfig controls whether and how online feature normalization
 	// is applied before passing feature vectors into the forest.
 	NormalizerConfig features.NormalizerConfig
+
+	// Schema optionally declares the named input columns of the model. It
+	// enables Predictor.PredictRecord / Predictor.UpdateRecord and makes
+	// feature names appear in explanations, decision paths and exports.
+	// If NumFeatures is zero, it defaults to the number of schema columns,
+	// or to the output dimension of the embedder if EmbedderFactory is set
+	// and its embedder implements features.DimensionedEmbedder.
+	Schema *features.Schema
 }
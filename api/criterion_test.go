@@ -0,0 +1,188 @@
+package onlinerf
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+// TestSplitCriteria проверяет, что все встроенные критерии выбирают
+// информативный признак для расщепления корня.
+func TestSplitCriteria(t *testing.T) {
+	criteria := map[string]SplitCriterion{
+		"default":   nil,
+		"gini":      GiniCriterion{},
+		"infogain":  InfoGainCriterion{},
+		"hellinger": HellingerCriterion{},
+	}
+
+	for name, c := range criteria {
+		pred := mustPredictor(PredictorConfig{
+			NumTrees:    1,
+			NumFeatures: 3,
+			TreeOptions: TreeOptions{
+				MaxDepth:            4,
+				HoeffdingSplitDelta: 0.01,
+				MinSamplesPerLeaf:   50,
+				SplitCriterion:      c,
+			},
+		})
+		trainSynthetic(pred, 2000, 10)
+
+		root := pred.trees[0].Root
+		if root.IsLeaf {
+			t.Fatalf("%s: expected root to split", name)
+		}
+		if root.SplitFeature != 0 {
+			t.Fatalf("%s: expected split on feature 0, got %d", name, root.SplitFeature)
+		}
+	}
+}
+
+// TestHellingerSkewedStream проверяет, что критерий Хеллингера находит
+// расщепление на сильно несбалансированном потоке.
+func TestHellingerSkewedStream(t *testing.T) {
+	pred := mustPredictor(PredictorConfig{
+		NumTrees:    1,
+		NumFeatures: 2,
+		TreeOptions: TreeOptions{
+			MaxDepth:            4,
+			HoeffdingSplitDelta: 0.01,
+			MinSamplesPerLeaf:   50,
+			SplitCriterion:      HellingerCriterion{},
+		},
+	})
+
+	rng := rand.New(rand.NewSource(11))
+	for i := 0; i < 3000; i++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64()}
+		pred.Update(fv, fv[1] > 0.95)
+	}
+
+	root := pred.trees[0].Root
+	if root.IsLeaf || root.SplitFeature != 1 {
+		t.Fatalf("expected split on the minority-class feature, leaf=%v feature=%d", root.IsLeaf, root.SplitFeature)
+	}
+}
+
+// TestTieThreshold проверяет, что при двух одинаково полезных признаках
+// лист расщепляется только при включённом пороге разрешения ничьих.
+func TestTieThreshold(t *testing.T) {
+	train := func(tau float64) *Predictor {
+		pred := mustPredictor(PredictorConfig{
+			NumTrees:    1,
+			NumFeatures: 2,
+			TreeOptions: TreeOptions{
+				MaxDepth:            4,
+				HoeffdingSplitDelta: 0.01,
+				MinSamplesPerLeaf:   20,
+				TieThreshold:        tau,
+			},
+		})
+		rng := rand.New(rand.NewSource(12))
+		for i := 0; i < 2000; i++ {
+			x := rng.Float64()
+			// Оба признака несут одинаковую информацию.
+			pred.Update(features.FeatureVector{x, x}, x > 0.5)
+		}
+		return pred
+	}
+
+	if root := train(0).trees[0].Root; !root.IsLeaf {
+		t.Fatalf("expected tied features to block the split without tau")
+	}
+	if root := train(0.05).trees[0].Root; root.IsLeaf {
+		t.Fatalf("expected tie threshold to force a split")
+	}
+}
+
+// TestGracePeriod проверяет, что попытки расщепления происходят только
+// раз в GracePeriod примеров.
+func TestGracePeriod(t *testing.T) {
+	pred := mustPredictor(PredictorConfig{
+		NumTrees:    1,
+		NumFeatures: 2,
+		TreeOptions: TreeOptions{
+			MaxDepth:            4,
+			HoeffdingSplitDelta: 1e-9,
+			MinSamplesPerLeaf:   1,
+			GracePeriod:         25,
+		},
+	})
+
+	for i := 0; i < 110; i++ {
+		pred.Update(features.FeatureVector{0.5, 0.5}, i%2 == 0)
+	}
+
+	root := pred.trees[0].Root
+	if root.LastSplitAttempt != 100 {
+		t.Fatalf("expected last split attempt at 100 samples, got %v", root.LastSplitAttempt)
+	}
+}
+
+// TestPrePruneNoisyStream проверяет, что с предобрезкой дерево не растёт
+// на шумовом потоке, а информативный признак по-прежнему расщепляется.
+func TestPrePruneNoisyStream(t *testing.T) {
+	newPred := func(prePrune bool) *Predictor {
+		return mustPredictor(PredictorConfig{
+			NumTrees:    1,
+			NumFeatures: 1,
+			TreeOptions: TreeOptions{
+				MaxDepth:            4,
+				HoeffdingSplitDelta: 0.01,
+				MinSamplesPerLeaf:   20,
+				PrePrune:            prePrune,
+			},
+		})
+	}
+
+	noisy := func(pred *Predictor) {
+		rng := rand.New(rand.NewSource(13))
+		for i := 0; i < 2000; i++ {
+			pred.Update(features.FeatureVector{rng.Float64()}, rng.Intn(2) == 0)
+		}
+	}
+
+	plain := newPred(false)
+	noisy(plain)
+	if plain.trees[0].Root.IsLeaf {
+		t.Fatalf("expected the tree without pre-pruning to split on noise")
+	}
+
+	pruned := newPred(true)
+	noisy(pruned)
+	if !pruned.trees[0].Root.IsLeaf {
+		t.Fatalf("expected pre-pruning to keep the root a leaf on noise")
+	}
+
+	informative := newPred(true)
+	rng := rand.New(rand.NewSource(14))
+	for i := 0; i < 2000; i++ {
+		x := rng.Float64()
+		informative.Update(features.FeatureVector{x}, x > 0.5)
+	}
+	if informative.trees[0].Root.IsLeaf {
+		t.Fatalf("expected pre-pruning to allow informative splits")
+	}
+}
+
+// TestMinSplitGain проверяет, что слишком малый прирост не приводит к
+// расщеплению.
+func TestMinSplitGain(t *testing.T) {
+	pred := mustPredictor(PredictorConfig{
+		NumTrees:    1,
+		NumFeatures: 3,
+		TreeOptions: TreeOptions{
+			MaxDepth:            4,
+			HoeffdingSplitDelta: 0.01,
+			MinSamplesPerLeaf:   50,
+			MinSplitGain:        0.9,
+		},
+	})
+	trainSynthetic(pred, 2000, 15)
+
+	if !pred.trees[0].Root.IsLeaf {
+		t.Fatalf("expected MinSplitGain to prevent splits")
+	}
+}
@@ -0,0 +1,131 @@
+package onlinerf
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+	"github.com/kudmo/onlinerf/internal/forest"
+)
+
+// firstDrift возвращает номер наблюдения, на котором детектор сообщил о
+// дрейфе после скачка среднего с 0 до 0.75 на сотом наблюдении, или -1.
+func firstDrift(d *forest.DriftDetector) int {
+	for i := 0; i < 1000; i++ {
+		if d.Add(i >= 100 && i%4 != 0) {
+			return i
+		}
+	}
+	return -1
+}
+
+// TestADWINStepChange фиксирует момент срабатывания обоих вариантов
+// детектора: неограниченный использует единый порог для всего окна, а
+// ограниченный — поправку ADWIN на число проверяемых разрезов и потому
+// срабатывает позже.
+func TestADWINStepChange(t *testing.T) {
+	if i := firstDrift(forest.NewADWIN(0.002)); i != 101 {
+		t.Fatalf("expected the unbounded detector to fire at 101, got %d", i)
+	}
+	if i := firstDrift(forest.NewBoundedADWIN(0.002, 1000)); i != 126 {
+		t.Fatalf("expected the bounded detector to fire at 126, got %d", i)
+	}
+}
+
+// TestADWINStationary проверяет отсутствие ложных срабатываний на
+// стационарном потоке.
+func TestADWINStationary(t *testing.T) {
+	for _, d := range []*forest.DriftDetector{
+		forest.NewADWIN(0.002),
+		forest.NewBoundedADWIN(0.002, 500),
+	} {
+		for i := 0; i < 1000; i++ {
+			if d.Add(i%2 == 0) {
+				t.Fatalf("unexpected drift at %d", i)
+			}
+		}
+		if e := d.Estimate(); e != 0.5 {
+			t.Fatalf("expected estimate 0.5, got %v", e)
+		}
+	}
+}
+
+// newDriftTree создаёт дерево из одного листа с детектором дрейфа.
+func newDriftTree(leaf forest.LeafPrediction) *forest.Tree {
+	return forest.NewTree(forest.TreeConfig{
+		MaxDepth:            0,
+		HoeffdingSplitDelta: 0.1,
+		UseDriftDetection:   true,
+		LeafPrediction:      leaf,
+	}, 2)
+}
+
+// TestLeafDriftStationary проверяет, что детектор листа не сбрасывает лист
+// на стационарном потоке ни при какой доле положительных меток.
+func TestLeafDriftStationary(t *testing.T) {
+	for _, rate := range []float64{0.01, 0.05, 0.2, 0.5} {
+		rng := rand.New(rand.NewSource(1))
+		tree := newDriftTree(forest.LeafMajorityClass)
+		const n = 20000
+		for i := 0; i < n; i++ {
+			tree.Update(features.FeatureVector{rng.Float64(), rng.Float64()}, rng.Float64() < rate)
+		}
+		if total := tree.Root.Stats.Total(); total != n {
+			t.Fatalf("rate %v: leaf was reset, %v of %d samples left", rate, total, n)
+		}
+	}
+}
+
+// TestLeafDriftReset проверяет, что сброс листа при дрейфе очищает все его
+// статистики и сохраняет пороги кандидатов.
+func TestLeafDriftReset(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tree := newDriftTree(forest.LeafNBAdaptive)
+	sample := func() features.FeatureVector {
+		return features.FeatureVector{rng.Float64(), rng.Float64()}
+	}
+
+	for i := 0; i < 500; i++ {
+		tree.Update(sample(), false)
+	}
+	thresholds := map[int]float64{}
+	for i, fs := range tree.Root.FeatureStats {
+		thresholds[i] = fs.Threshold
+	}
+
+	reset := false
+	for i := 0; i < 500 && !reset; i++ {
+		tree.Update(sample(), true)
+		reset = tree.Root.Stats.Total() == 0
+	}
+	if !reset {
+		t.Fatal("expected the leaf to be reset after the label switch")
+	}
+
+	leaf := tree.Root
+	if leaf.MCCorrect != 0 || leaf.NBCorrect != 0 {
+		t.Fatalf("expected leaf accuracy to be reset, got MC=%v NB=%v", leaf.MCCorrect, leaf.NBCorrect)
+	}
+	if len(leaf.FeatureStats) != len(thresholds) {
+		t.Fatalf("expected %d feature stats, got %d", len(thresholds), len(leaf.FeatureStats))
+	}
+	for i, fs := range leaf.FeatureStats {
+		if fs.Threshold != thresholds[i] {
+			t.Fatalf("feature %d: threshold changed from %v to %v", i, thresholds[i], fs.Threshold)
+		}
+		left, right := fs.Counts()
+		if left.Pos+left.Neg+right.Pos+right.Neg != 0 {
+			t.Fatalf("feature %d: stale counts %v %v after reset", i, left, right)
+		}
+	}
+
+	for i := 0; i < 50; i++ {
+		tree.Update(sample(), true)
+	}
+	for i, fs := range leaf.FeatureStats {
+		left, right := fs.Counts()
+		if left.Pos+right.Pos != leaf.Stats.Pos || left.Neg+right.Neg != leaf.Stats.Neg {
+			t.Fatalf("feature %d: counts %v %v disagree with leaf %+v", i, left, right, leaf.Stats)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package onlinerf
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+// trainConceptSwitch обучает модель сначала на понятии x0 > 0.5, затем
+// на понятии x1 > 0.5. Первый пример задаёт пороги 0.5 в корне.
+func trainConceptSwitch(pred *Predictor) {
+	rng := rand.New(rand.NewSource(43))
+	pred.Update(features.FeatureVector{0.5, 0.5}, false)
+	for i := 0; i < 1000; i++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64()}
+		pred.Update(fv, fv[0] > 0.5)
+	}
+	for i := 0; i < 5000; i++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64()}
+		pred.Update(fv, fv[1] > 0.5)
+	}
+}
+
+// TestEFDTReplacesOutdatedSplit проверяет, что в режиме EFDT корень
+// заменяет разбиение, когда другой признак становится значимо лучше, а в
+// режиме VFDT разбиение остаётся прежним.
+func TestEFDTReplacesOutdatedSplit(t *testing.T) {
+	for mode, wantFeature := range map[SplitMode]int{SplitVFDT: 0, SplitEFDT: 1} {
+		pred := mustPredictor(PredictorConfig{
+			NumTrees:    1,
+			NumFeatures: 2,
+			TreeOptions: TreeOptions{
+				MaxDepth:            1,
+				MinSamplesPerLeaf:   50,
+				HoeffdingSplitDelta: 1e-4,
+				GracePeriod:         50,
+				SplitMode:           mode,
+			},
+		})
+		trainConceptSwitch(pred)
+
+		root := pred.trees[0].Root
+		if root.IsLeaf || root.SplitFeature != wantFeature {
+			t.Fatalf("mode %d: expected root split on feature %d, got leaf=%v feature=%d",
+				mode, wantFeature, root.IsLeaf, root.SplitFeature)
+		}
+	}
+}
+
+// TestEFDTSplitsEarlierThanVFDT проверяет, что EFDT делает первое
+// разбиение, не дожидаясь отрыва от второго по качеству признака.
+func TestEFDTSplitsEarlierThanVFDT(t *testing.T) {
+	for mode, wantLeaf := range map[SplitMode]bool{SplitVFDT: true, SplitEFDT: false} {
+		pred := mustPredictor(PredictorConfig{
+			NumTrees:    1,
+			NumFeatures: 2,
+			TreeOptions: TreeOptions{
+				MaxDepth:            1,
+				MinSamplesPerLeaf:   50,
+				HoeffdingSplitDelta: 1e-4,
+				GracePeriod:         50,
+				SplitMode:           mode,
+			},
+		})
+		// Оба признака одинаково информативны: x0 == x1.
+		rng := rand.New(rand.NewSource(44))
+		pred.Update(features.FeatureVector{0.5, 0.5}, false)
+		for i := 0; i < 500; i++ {
+			x := rng.Float64()
+			pred.Update(features.FeatureVector{x, x}, x > 0.5)
+		}
+
+		if leaf := pred.trees[0].Root.IsLeaf; leaf != wantLeaf {
+			t.Fatalf("mode %d: expected root leaf=%v, got %v", mode, wantLeaf, leaf)
+		}
+	}
+}
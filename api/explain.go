@@ -0,0 +1,89 @@
+package onlinerf
+
+import "github.com/kudmo/onlinerf/api/features"
+
+// Explanation describes how individual features contributed to a single
+// prediction of the forest.
+//
+// Contributions are SHAP values computed with TreeSHAP and averaged across
+// all trees, so that
+//
+//	BaseValue + sum(Contributions) == Prediction
+//
+// up to floating point rounding.
+type Explanation struct {
+	// BaseValue is the expected forest output, i.e. the average over trees
+	// of the training-weighted mean leaf prediction (the model's base rate).
+	BaseValue float64
+
+	// Prediction is the forest output for the explained feature vector.
+	Prediction float64
+
+	// Contributions holds one value per embedded feature. Positive values
+	// push the prediction towards the positive class.
+	Contributions []float64
+
+	// FeatureNames names the entries of Contributions when the predictor
+	// has a schema (see Predictor.FeatureNames), and is nil otherwise.
+	FeatureNames []string
+}
+
+// Explain returns per-feature contributions for the prediction of fv.
+//
+// The feature vector goes through the same pipeline as in Predict. Trees
+// that have not seen any data contribute their constant prior prediction to
+// both BaseValue and Prediction and nothing to Contributions.
+//
+// Example:
+//
+//	exp := model.Explain(fv)
+//	for i, c := range exp.Contributions {
+//		fmt.Printf("feature %d: %+.3f\n", i, c)
+//	}
+func (p *Predictor) Explain(fv features.FeatureVector) Explanation {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	embedded := fv
+	if p.normalizer != nil {
+		embedded = p.normalizer.Transform(embedded)
+	}
+
+	exp := Explanation{
+		Contributions: make([]float64, p.numFeatures),
+		FeatureNames:  p.FeatureNames(),
+	}
+
+	n := 0
+	for j, t := range p.trees {
+		if t == nil {
+			continue
+		}
+		phi, base := t.SHAP(embedded)
+		sign := 1.0
+		if p.flipped(j) {
+			// The tree learned the negated label: p = 1 - (base + sum(phi)).
+			sign = -1
+			base = 1 - base
+		}
+		for i, v := range phi {
+			exp.Contributions[i] += sign * v
+		}
+		exp.BaseValue += base
+		exp.Prediction += p.treePredict(j, embedded)
+		n++
+	}
+
+	if n == 0 {
+		exp.BaseValue = 0.5
+		exp.Prediction = 0.5
+		return exp
+	}
+
+	for i := range exp.Contributions {
+		exp.Contributions[i] /= float64(n)
+	}
+	exp.BaseValue /= float64(n)
+	exp.Prediction /= float64(n)
+	return exp
+}
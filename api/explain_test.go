@@ -0,0 +1,73 @@
+package onlinerf
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+// trainSynthetic обучает предиктор на простом синтетическом потоке,
+// где метка зависит только от первого признака.
+func trainSynthetic(pred *Predictor, n int, seed int64) {
+	rng := rand.New(rand.NewSource(seed))
+	for i := 0; i < n; i++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64(), rng.Float64()}
+		pred.Update(fv, fv[0] > 0.5)
+	}
+}
+
+// TestExplainSumsToPrediction проверяет, что сумма вкладов признаков
+// равна разнице между предсказанием и базовым значением.
+func TestExplainSumsToPrediction(t *testing.T) {
+	cfg := PredictorConfig{
+		NumTrees:    3,
+		NumFeatures: 3,
+		TreeOptions: TreeOptions{
+			MaxDepth:            6,
+			MaxNodesPerTree:     100,
+			HoeffdingSplitDelta: 0.1,
+			MinSamplesPerLeaf:   10,
+		},
+	}
+	pred := mustPredictor(cfg)
+	trainSynthetic(pred, 2000, 1)
+
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 50; i++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64(), rng.Float64()}
+		exp := pred.Explain(fv)
+
+		if len(exp.Contributions) != cfg.NumFeatures {
+			t.Fatalf("expected %d contributions, got %d", cfg.NumFeatures, len(exp.Contributions))
+		}
+		if got := pred.Predict(fv); math.Abs(got-exp.Prediction) > 1e-9 {
+			t.Fatalf("explanation prediction %v differs from Predict %v", exp.Prediction, got)
+		}
+
+		sum := exp.BaseValue
+		for _, c := range exp.Contributions {
+			sum += c
+		}
+		if math.Abs(sum-exp.Prediction) > 1e-9 {
+			t.Fatalf("base + contributions = %v, prediction = %v", sum, exp.Prediction)
+		}
+	}
+}
+
+// TestExplainUntrained проверяет, что для необученной модели все вклады
+// нулевые, а базовое значение совпадает с предсказанием.
+func TestExplainUntrained(t *testing.T) {
+	pred := mustPredictor(PredictorConfig{NumTrees: 2, NumFeatures: 2, TreeOptions: TreeOptions{MaxDepth: 3}})
+
+	exp := pred.Explain(features.FeatureVector{0.1, 0.2})
+	if exp.BaseValue != exp.Prediction {
+		t.Fatalf("expected base value %v to equal prediction %v", exp.BaseValue, exp.Prediction)
+	}
+	for i, c := range exp.Contributions {
+		if c != 0 {
+			t.Fatalf("expected zero contribution for feature %d, got %v", i, c)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package onlinerf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/kudmo/onlinerf/internal/forest"
+)
+
+// ExportOptions controls ExportJSON and ExportDOT.
+type ExportOptions struct {
+	// FeatureNames optionally maps embedded feature indices to names used
+	// in the rendered splits. If nil, Predictor.FeatureNames is used.
+	// Missing entries fall back to "x[i]".
+	FeatureNames []string
+
+	// Trees selects the trees to export by index. If empty, the whole
+	// forest is exported.
+	Trees []int
+}
+
+type forestDocument struct {
+	NumTrees     int            `json:"num_trees"`
+	NumFeatures  int            `json:"num_features"`
+	FeatureNames []string       `json:"feature_names,omitempty"`
+	Trees        []treeDocument `json:"trees"`
+}
+
+type treeDocument struct {
+	Index int `json:"index"`
+	forest.TreeExport
+	// BackgroundRNGState is the random stream of the tree's background
+	// tree in EnsembleRandomPatches mode.
+	BackgroundRNGState *uint64 `json:"background_rng_state,omitempty,string"`
+}
+
+// ExportJSON writes the selected trees as an indented JSON document with the
+// split feature, threshold, depth, label counts and drift detector status of
+// every node. The document layout is stable and suitable for diffing.
+//
+// Example:
+//
+//	err := model.ExportJSON(os.Stdout, onlinerf.ExportOptions{
+//		FeatureNames: []string{"cpu", "mem"},
+//	})
+func (p *Predictor) ExportJSON(w io.Writer, opts ExportOptions) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	indices, err := p.exportIndices(opts.Trees)
+	if err != nil {
+		return err
+	}
+	if opts.FeatureNames == nil {
+		opts.FeatureNames = p.FeatureNames()
+	}
+
+	doc := forestDocument{
+		NumTrees:     len(p.trees),
+		NumFeatures:  p.numFeatures,
+		FeatureNames: opts.FeatureNames,
+		Trees:        make([]treeDocument, 0, len(indices)),
+	}
+	for _, i := range indices {
+		d := treeDocument{
+			Index:      i,
+			TreeExport: *p.exportTree(i, opts.FeatureNames),
+		}
+		if b := p.background(i); b != nil {
+			state := b.Rand.State()
+			d.BackgroundRNGState = &state
+		}
+		doc.Trees = append(doc.Trees, d)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// ExportDOT writes the selected trees as a Graphviz digraph, one cluster per
+// tree. Render it with e.g. `dot -Tsvg forest.dot -o forest.svg`.
+func (p *Predictor) ExportDOT(w io.Writer, opts ExportOptions) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	indices, err := p.exportIndices(opts.Trees)
+	if err != nil {
+		return err
+	}
+	if opts.FeatureNames == nil {
+		opts.FeatureNames = p.FeatureNames()
+	}
+
+	exports := make([]*forest.TreeExport, len(p.trees))
+	for _, i := range indices {
+		exports[i] = p.exportTree(i, opts.FeatureNames)
+	}
+	return forest.WriteExportsDOT(w, exports)
+}
+
+// exportTree exports tree i in terms of the original labels, undoing its
+// output code. Callers must hold p.mu.
+func (p *Predictor) exportTree(i int, names []string) *forest.TreeExport {
+	e := p.trees[i].Export(names)
+	if p.flipped(i) {
+		e.Invert()
+	}
+	return &e
+}
+
+func (p *Predictor) exportIndices(selected []int) ([]int, error) {
+	if len(selected) == 0 {
+		all := make([]int, 0, len(p.trees))
+		for i, t := range p.trees {
+			if t != nil {
+				all = append(all, i)
+			}
+		}
+		return all, nil
+	}
+
+	for _, i := range selected {
+		if i < 0 || i >= len(p.trees) || p.trees[i] == nil {
+			return nil, fmt.Errorf("onlinerf: tree index %d out of range", i)
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,266 @@
+package onlinerf
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+	"github.com/kudmo/onlinerf/internal/forest"
+)
+
+// TestExportJSON проверяет, что экспорт в JSON содержит все деревья,
+// имена признаков и согласованные счётчики листьев.
+func TestExportJSON(t *testing.T) {
+	cfg := PredictorConfig{
+		NumTrees:    2,
+		NumFeatures: 3,
+		TreeOptions: TreeOptions{
+			MaxDepth:            6,
+			MaxNodesPerTree:     100,
+			HoeffdingSplitDelta: 0.1,
+			MinSamplesPerLeaf:   10,
+		},
+	}
+	pred := mustPredictor(cfg)
+	trainSynthetic(pred, 1000, 4)
+
+	var buf bytes.Buffer
+	opts := ExportOptions{FeatureNames: []string{"cpu", "mem", "disk"}}
+	if err := pred.ExportJSON(&buf, opts); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	var doc struct {
+		NumTrees int `json:"num_trees"`
+		Trees    []struct {
+			Index    int `json:"index"`
+			NumNodes int `json:"num_nodes"`
+			Root     struct {
+				FeatureName string `json:"feature_name"`
+				Pos         int    `json:"pos"`
+				Neg         int    `json:"neg"`
+			} `json:"root"`
+		} `json:"trees"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if len(doc.Trees) != cfg.NumTrees {
+		t.Fatalf("expected %d trees, got %d", cfg.NumTrees, len(doc.Trees))
+	}
+	for _, tr := range doc.Trees {
+		if tr.NumNodes < 3 {
+			t.Fatalf("expected tree %d to have split, got %d nodes", tr.Index, tr.NumNodes)
+		}
+		if tr.Root.FeatureName != "cpu" {
+			t.Fatalf("expected root split on cpu, got %q", tr.Root.FeatureName)
+		}
+		if tr.Root.Pos+tr.Root.Neg == 0 {
+			t.Fatalf("expected aggregated counts at the root")
+		}
+	}
+
+	// Повторный экспорт должен быть побайтно идентичен.
+	var again bytes.Buffer
+	_ = pred.ExportJSON(&again, opts)
+	if !bytes.Equal(buf.Bytes(), again.Bytes()) {
+		t.Fatalf("json export is not stable")
+	}
+}
+
+// TestExportDOT проверяет экспорт выбранного дерева в формат Graphviz
+// и ошибку для несуществующего индекса.
+func TestExportDOT(t *testing.T) {
+	pred := mustPredictor(PredictorConfig{
+		NumTrees:    2,
+		NumFeatures: 3,
+		TreeOptions: TreeOptions{
+			MaxDepth:            6,
+			HoeffdingSplitDelta: 0.1,
+			MinSamplesPerLeaf:   10,
+		},
+	})
+	trainSynthetic(pred, 1000, 5)
+
+	var buf bytes.Buffer
+	if err := pred.ExportDOT(&buf, ExportOptions{Trees: []int{1}}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "digraph forest {") {
+		t.Fatalf("unexpected DOT header: %q", out)
+	}
+	if !strings.Contains(out, "cluster_1") || strings.Contains(out, "cluster_0") {
+		t.Fatalf("expected only tree 1 to be exported:\n%s", out)
+	}
+	if !strings.Contains(out, "x[0] <=") {
+		t.Fatalf("expected split on x[0]:\n%s", out)
+	}
+
+	if err := pred.ExportDOT(&buf, ExportOptions{Trees: []int{5}}); err == nil {
+		t.Fatalf("expected error for out-of-range tree index")
+	}
+}
+
+// TestExportDriftStatus проверяет, что настройки детектора дрейфа доходят
+// до деревьев и его состояние попадает в экспорт каждого листа.
+func TestExportDriftStatus(t *testing.T) {
+	pred := mustPredictor(PredictorConfig{
+		NumTrees:    1,
+		NumFeatures: 3,
+		TreeOptions: TreeOptions{
+			MaxDepth:            4,
+			HoeffdingSplitDelta: 0.1,
+			MinSamplesPerLeaf:   10,
+			UseDriftDetection:   true,
+			DriftAlpha:          0.001,
+		},
+	})
+	trainSynthetic(pred, 1000, 6)
+
+	e := pred.trees[0].Export(nil)
+	if e.NumLeaves < 2 {
+		t.Fatalf("expected the tree to split, got %d leaves", e.NumLeaves)
+	}
+	var walk func(n *forest.NodeExport)
+	walk = func(n *forest.NodeExport) {
+		if !n.Leaf {
+			walk(n.Left)
+			walk(n.Right)
+			return
+		}
+		if n.Drift == nil {
+			t.Fatalf("expected drift status at leaf %d", n.ID)
+		}
+	}
+	walk(e.Root)
+
+	var buf bytes.Buffer
+	if err := pred.ExportDOT(&buf, ExportOptions{}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "drift w=") {
+		t.Fatalf("expected drift status in DOT output:\n%s", buf.String())
+	}
+}
+
+// TestExportLeafPrediction проверяет, что экспорт листа показывает
+// предсказание дерева с учётом выходного кода, а не сырые счётчики.
+func TestExportLeafPrediction(t *testing.T) {
+	pred := mustPredictor(PredictorConfig{
+		NumTrees:    6,
+		NumFeatures: 2,
+		TreeOptions: TreeOptions{
+			MaxDepth:          2,
+			MinSamplesPerLeaf: 1 << 30,
+		},
+		Ensemble:   EnsembleLeveraging,
+		Leveraging: LeveragingConfig{OutputCodes: true},
+	})
+	rng := rand.New(rand.NewSource(7))
+	for i := 0; i < 200; i++ {
+		pred.Update(features.FeatureVector{rng.Float64(), rng.Float64()}, true)
+	}
+
+	flipped := -1
+	for i := range pred.trees {
+		if pred.flipped(i) {
+			flipped = i
+		}
+	}
+	if flipped < 0 {
+		t.Fatalf("expected at least one tree with a flipped output code")
+	}
+
+	var buf bytes.Buffer
+	if err := pred.ExportJSON(&buf, ExportOptions{Trees: []int{flipped}}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	var doc struct {
+		Trees []struct {
+			Root struct {
+				Pos        float64  `json:"pos"`
+				Neg        float64  `json:"neg"`
+				Prediction *float64 `json:"prediction"`
+			} `json:"root"`
+		} `json:"trees"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	root := doc.Trees[0].Root
+	if root.Prediction == nil || *root.Prediction != 1 || root.Neg != 0 || root.Pos == 0 {
+		t.Fatalf("expected the flipped tree to be exported in original labels, got %+v", root)
+	}
+
+	buf.Reset()
+	if err := pred.ExportDOT(&buf, ExportOptions{Trees: []int{flipped}}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "p=1.000") {
+		t.Fatalf("expected decoded leaf probability in DOT output:\n%s", buf.String())
+	}
+}
+
+// TestExportInvertsLeafModel проверяет, что экспорт дерева с
+// инвертированным кодом содержит модель листа, предсказывающую исходный
+// класс, и не меняет саму модель дерева.
+func TestExportInvertsLeafModel(t *testing.T) {
+	pred := mustPredictor(PredictorConfig{
+		NumTrees:    6,
+		NumFeatures: 2,
+		TreeOptions: TreeOptions{
+			MaxDepth:          2,
+			MinSamplesPerLeaf: 1 << 30,
+			LeafPrediction:    LeafLogistic,
+		},
+		Ensemble:   EnsembleLeveraging,
+		Leveraging: LeveragingConfig{OutputCodes: true},
+	})
+	rng := rand.New(rand.NewSource(8))
+	for i := 0; i < 2000; i++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64()}
+		pred.Update(fv, fv[0] > 0.5)
+	}
+
+	flipped := -1
+	for i := range pred.trees {
+		if pred.flipped(i) {
+			flipped = i
+		}
+	}
+	if flipped < 0 {
+		t.Fatalf("expected at least one tree with a flipped output code")
+	}
+
+	fv := features.FeatureVector{0.9, 0.3}
+	want := pred.treePredict(flipped, fv)
+	if want < 0.5 {
+		t.Fatalf("expected the decoded tree to predict positive, got %v", want)
+	}
+
+	var buf bytes.Buffer
+	if err := pred.ExportJSON(&buf, ExportOptions{Trees: []int{flipped}}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	var doc struct {
+		Trees []forest.TreeExport `json:"trees"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	model := doc.Trees[0].Root.Model
+	if model == nil {
+		t.Fatalf("expected the leaf model to be exported")
+	}
+	if got := model.Prob(fv); math.Abs(got-want) > 1e-9 {
+		t.Fatalf("exported model predicts %v, tree predicts %v", got, want)
+	}
+	if got := pred.treePredict(flipped, fv); got != want {
+		t.Fatalf("export changed the tree's prediction from %v to %v", want, got)
+	}
+}
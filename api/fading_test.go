@@ -0,0 +1,48 @@
+package onlinerf
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+// TestFadingFactorForgetsOldSamples проверяет, что с коэффициентом
+// затухания лист следует за недавней долей положительных примеров, а без
+// него остаётся во власти старых данных.
+func TestFadingFactorForgetsOldSamples(t *testing.T) {
+	train := func(fading float64) *Predictor {
+		pred := mustPredictor(PredictorConfig{
+			NumTrees:    1,
+			NumFeatures: 1,
+			TreeOptions: TreeOptions{
+				MaxDepth:          3,
+				MinSamplesPerLeaf: 1 << 30,
+				FadingFactor:      fading,
+			},
+		})
+		rng := rand.New(rand.NewSource(42))
+		for i := 0; i < 3000; i++ {
+			pred.Update(features.FeatureVector{rng.Float64()}, rng.Float64() < 0.9)
+		}
+		for i := 0; i < 500; i++ {
+			pred.Update(features.FeatureVector{rng.Float64()}, rng.Float64() < 0.1)
+		}
+		return pred
+	}
+
+	fv := features.FeatureVector{0.5}
+	if p := train(0).Predict(fv); p < 0.6 {
+		t.Fatalf("expected counts without decay to be dominated by old data, got %v", p)
+	}
+
+	faded := train(0.99)
+	if p := faded.Predict(fv); math.Abs(p-0.1) > 0.05 {
+		t.Fatalf("expected decayed leaf to track the recent rate 0.1, got %v", p)
+	}
+	// Окно затухания ~1/(1-0.99) = 100 примеров.
+	if total := faded.trees[0].Root.Stats.Total(); total > 110 {
+		t.Fatalf("expected decayed weight near 100, got %v", total)
+	}
+}
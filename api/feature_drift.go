@@ -0,0 +1,199 @@
+package onlinerf
+
+import "github.com/kudmo/onlinerf/internal/monitor"
+
+// FeatureDriftMethod selects how input (covariate) drift is measured.
+type FeatureDriftMethod int
+
+const (
+	// FeatureDriftNone disables input drift monitoring.
+	FeatureDriftNone FeatureDriftMethod = iota
+	// FeatureDriftPSI compares a recent window with a reference window using
+	// the population stability index over reference-quantile bins.
+	FeatureDriftPSI
+	// FeatureDriftKS compares a recent window with a reference window using
+	// the two-sample Kolmogorov–Smirnov test.
+	FeatureDriftKS
+	// FeatureDriftPageHinkley runs a two-sided Page-Hinkley test on the mean
+	// of every feature.
+	FeatureDriftPageHinkley
+)
+
+// String returns a short name of the method for logging.
+func (m FeatureDriftMethod) String() string {
+	switch m {
+	case FeatureDriftPSI:
+		return "psi"
+	case FeatureDriftKS:
+		return "ks"
+	case FeatureDriftPageHinkley:
+		return "page-hinkley"
+	default:
+		return "none"
+	}
+}
+
+// FeatureDriftConfig configures input drift monitoring. Inputs are
+// monitored after embedding and normalization, i.e. as seen by the trees.
+type FeatureDriftConfig struct {
+	// Method selects the drift statistic. Defaults to FeatureDriftNone.
+	Method FeatureDriftMethod
+
+	// MonitorPredict also feeds inputs passed to Predict and
+	// PredictCalibrated into the monitor, so drift is visible before any
+	// label arrives. By default only inputs passed to Update are monitored.
+	MonitorPredict bool
+
+	// ReferenceSize is the number of initial inputs forming the reference
+	// distribution for PSI and KS. Defaults to 1000.
+	ReferenceSize int
+
+	// WindowSize is the size of the sliding window of recent inputs
+	// compared with the reference for PSI and KS. Defaults to 500.
+	WindowSize int
+
+	// Bins is the number of quantile bins used by PSI. Defaults to 10.
+	Bins int
+
+	// PSIThreshold is the PSI above which a feature is reported as drifted.
+	// Defaults to 0.2.
+	PSIThreshold float64
+
+	// KSAlpha is the significance level of the KS test. Defaults to 0.01.
+	KSAlpha float64
+
+	// PHDelta is the magnitude of mean changes tolerated by Page-Hinkley.
+	// Defaults to 0.005.
+	PHDelta float64
+
+	// PHLambda is the Page-Hinkley detection threshold. Defaults to 50.
+	PHLambda float64
+}
+
+func (c FeatureDriftConfig) withDefaults() FeatureDriftConfig {
+	if c.ReferenceSize <= 0 {
+		c.ReferenceSize = 1000
+	}
+	if c.WindowSize <= 0 {
+		c.WindowSize = 500
+	}
+	if c.Bins <= 1 {
+		c.Bins = 10
+	}
+	if c.PSIThreshold <= 0 {
+		c.PSIThreshold = 0.2
+	}
+	if c.KSAlpha <= 0 {
+		c.KSAlpha = 0.01
+	}
+	if c.PHDelta <= 0 {
+		c.PHDelta = 0.005
+	}
+	if c.PHLambda <= 0 {
+		c.PHLambda = 50
+	}
+	return c
+}
+
+// FeatureDrift is the drift status of a single embedded feature.
+type FeatureDrift struct {
+	// Feature is the index of the feature in the embedded vector.
+	Feature int
+	// Name is the feature name when the predictor has a schema.
+	Name string
+	// Score is the value of the drift statistic (PSI, KS distance or
+	// Page-Hinkley statistic).
+	Score float64
+	// Threshold is the value Score is compared against.
+	Threshold float64
+	// Drifted reports whether Score exceeds Threshold.
+	Drifted bool
+	// Ready reports whether enough inputs were seen to compute Score.
+	Ready bool
+}
+
+// FeatureDriftReport summarizes input drift across all features.
+type FeatureDriftReport struct {
+	Method FeatureDriftMethod
+	// Samples is the number of inputs seen by the monitor.
+	Samples int64
+	// Features holds one entry per embedded feature.
+	Features []FeatureDrift
+}
+
+// Drifted returns the features reported as drifted, ordered by index.
+func (r FeatureDriftReport) Drifted() []FeatureDrift {
+	var out []FeatureDrift
+	for _, f := range r.Features {
+		if f.Drifted {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func newFeatureMonitor(cfg FeatureDriftConfig, numFeatures int) *monitor.Monitor {
+	var method monitor.Method
+	switch cfg.Method {
+	case FeatureDriftPSI:
+		method = monitor.PSI
+	case FeatureDriftKS:
+		method = monitor.KS
+	case FeatureDriftPageHinkley:
+		method = monitor.PageHinkley
+	default:
+		return nil
+	}
+	return monitor.New(monitor.Config{
+		Method:        method,
+		ReferenceSize: cfg.ReferenceSize,
+		WindowSize:    cfg.WindowSize,
+		Bins:          cfg.Bins,
+		PSIThreshold:  cfg.PSIThreshold,
+		Alpha:         cfg.KSAlpha,
+		Delta:         cfg.PHDelta,
+		Lambda:        cfg.PHLambda,
+	}, numFeatures)
+}
+
+// FeatureDrift returns the current input drift report. With monitoring
+// disabled the report has no features.
+func (p *Predictor) FeatureDrift() FeatureDriftReport {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	report := FeatureDriftReport{Method: p.cfg.FeatureDrift.Method}
+	if p.featureMonitor == nil {
+		return report
+	}
+
+	names := p.FeatureNames()
+	report.Samples = p.featureMonitor.Samples()
+	for i, r := range p.featureMonitor.Results() {
+		fd := FeatureDrift{
+			Feature:   i,
+			Score:     r.Score,
+			Threshold: r.Threshold,
+			Drifted:   r.Drifted,
+			Ready:     r.Ready,
+		}
+		if i < len(names) {
+			fd.Name = names[i]
+		}
+		report.Features = append(report.Features, fd)
+	}
+	return report
+}
+
+// RebaseFeatureDrift makes the recent window the new reference distribution
+// and restarts Page-Hinkley tests, e.g. once a reported drift is handled.
+// Monitoring continues without a warm-up: the report stays ready and
+// compares new inputs against the rebased reference.
+func (p *Predictor) RebaseFeatureDrift() {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.featureMonitor != nil {
+		p.featureMonitor.Rebase()
+	}
+}
@@ -0,0 +1,160 @@
+package onlinerf
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+// TestFeatureDriftDetectsShiftedFeature проверяет, что каждый метод
+// обнаруживает сдвиг распределения одного признака и не помечает
+// остальные.
+func TestFeatureDriftDetectsShiftedFeature(t *testing.T) {
+	for _, method := range []FeatureDriftMethod{FeatureDriftPSI, FeatureDriftKS, FeatureDriftPageHinkley} {
+		pred := mustPredictor(PredictorConfig{
+			NumTrees:    2,
+			NumFeatures: 3,
+			TreeOptions: TreeOptions{
+				MaxDepth:          3,
+				MinSamplesPerLeaf: 50,
+			},
+			FeatureDrift: FeatureDriftConfig{Method: method, ReferenceSize: 1000, WindowSize: 300},
+		})
+
+		rng := rand.New(rand.NewSource(40))
+		sample := func(shift float64) features.FeatureVector {
+			return features.FeatureVector{rng.Float64(), rng.Float64() + shift, rng.Float64()}
+		}
+		for i := 0; i < 1300; i++ {
+			fv := sample(0)
+			pred.Update(fv, fv[0] > 0.5)
+		}
+		if d := pred.FeatureDrift().Drifted(); len(d) != 0 {
+			t.Fatalf("%s: unexpected drift before the shift: %+v", method, d)
+		}
+
+		for i := 0; i < 300; i++ {
+			fv := sample(0.5)
+			pred.Update(fv, fv[0] > 0.5)
+		}
+		report := pred.FeatureDrift()
+		if report.Samples != 1600 || len(report.Features) != 3 {
+			t.Fatalf("%s: unexpected report %+v", method, report)
+		}
+		d := report.Drifted()
+		if len(d) != 1 || d[0].Feature != 1 {
+			t.Fatalf("%s: expected only feature 1 to drift, got %+v", method, report.Features)
+		}
+
+		pred.RebaseFeatureDrift()
+		if d := pred.FeatureDrift().Drifted(); len(d) != 0 {
+			t.Fatalf("%s: unexpected drift after rebasing: %+v", method, d)
+		}
+	}
+}
+
+// TestFeatureDriftRebase проверяет, что после перебазирования отчёт сразу
+// готов, новая эталонная выборка — последнее окно, и следующий сдвиг
+// снова обнаруживается через одно окно.
+func TestFeatureDriftRebase(t *testing.T) {
+	for _, method := range []FeatureDriftMethod{FeatureDriftPSI, FeatureDriftKS} {
+		pred := mustPredictor(PredictorConfig{
+			NumTrees:    1,
+			NumFeatures: 1,
+			TreeOptions: TreeOptions{
+				MaxDepth: 3,
+			},
+			FeatureDrift: FeatureDriftConfig{Method: method, ReferenceSize: 100, WindowSize: 50},
+		})
+
+		rng := rand.New(rand.NewSource(43))
+		feed := func(n int, shift float64) {
+			for i := 0; i < n; i++ {
+				pred.Update(features.FeatureVector{rng.Float64() + shift}, false)
+			}
+		}
+		feed(100, 0)
+		feed(50, 1)
+		if d := pred.FeatureDrift().Drifted(); len(d) != 1 {
+			t.Fatalf("%s: expected drift before rebasing, got %+v", method, pred.FeatureDrift().Features)
+		}
+
+		pred.RebaseFeatureDrift()
+		f := pred.FeatureDrift().Features[0]
+		if !f.Ready || f.Drifted {
+			t.Fatalf("%s: expected a ready report without drift after rebasing, got %+v", method, f)
+		}
+
+		// Новые данные из сдвинутого распределения совпадают с эталоном.
+		feed(50, 1)
+		if f := pred.FeatureDrift().Features[0]; !f.Ready || f.Drifted {
+			t.Fatalf("%s: unexpected drift against the rebased reference: %+v", method, f)
+		}
+		feed(50, 2)
+		if f := pred.FeatureDrift().Features[0]; !f.Drifted {
+			t.Fatalf("%s: expected the next shift to be detected, got %+v", method, f)
+		}
+	}
+}
+
+// TestFeatureDriftFromPredict проверяет, что при MonitorPredict сдвиг
+// виден по одним лишь входам Predict, без меток.
+func TestFeatureDriftFromPredict(t *testing.T) {
+	pred := mustPredictor(PredictorConfig{
+		NumTrees:    1,
+		NumFeatures: 2,
+		TreeOptions: TreeOptions{
+			MaxDepth: 3,
+		},
+		FeatureDrift: FeatureDriftConfig{Method: FeatureDriftKS, MonitorPredict: true, ReferenceSize: 500, WindowSize: 200},
+	})
+
+	rng := rand.New(rand.NewSource(41))
+	for i := 0; i < 500; i++ {
+		pred.Predict(features.FeatureVector{rng.Float64(), rng.NormFloat64()})
+	}
+	for i := 0; i < 200; i++ {
+		pred.Predict(features.FeatureVector{rng.Float64() * 0.5, rng.NormFloat64()})
+	}
+
+	d := pred.FeatureDrift().Drifted()
+	if len(d) != 1 || d[0].Feature != 0 || d[0].Score <= d[0].Threshold {
+		t.Fatalf("expected feature 0 to drift, got %+v", d)
+	}
+}
+
+// TestFeatureDriftPredictMethods проверяет, что Predict и PredictCalibrated
+// одинаково передают входы монитору признаков.
+func TestFeatureDriftPredictMethods(t *testing.T) {
+	pred := mustPredictor(PredictorConfig{
+		NumTrees:     1,
+		NumFeatures:  2,
+		TreeOptions:  TreeOptions{MaxDepth: 3},
+		Calibration:  CalibrationConfig{Method: CalibrationPlatt},
+		FeatureDrift: FeatureDriftConfig{Method: FeatureDriftPSI, MonitorPredict: true},
+	})
+
+	fv := features.FeatureVector{0.2, 0.4}
+	for i := 0; i < 10; i++ {
+		pred.Predict(fv)
+	}
+	if n := pred.FeatureDrift().Samples; n != 10 {
+		t.Fatalf("expected 10 monitored samples after Predict, got %d", n)
+	}
+	for i := 0; i < 10; i++ {
+		pred.PredictCalibrated(fv)
+	}
+	if n := pred.FeatureDrift().Samples; n != 20 {
+		t.Fatalf("expected 20 monitored samples after PredictCalibrated, got %d", n)
+	}
+}
+
+// TestFeatureDriftDisabled проверяет, что без настройки отчёт пуст.
+func TestFeatureDriftDisabled(t *testing.T) {
+	pred := mustPredictor(PredictorConfig{NumTrees: 1, NumFeatures: 3, TreeOptions: TreeOptions{MaxDepth: 3}})
+	trainSynthetic(pred, 100, 42)
+	if r := pred.FeatureDrift(); r.Method != FeatureDriftNone || len(r.Features) != 0 {
+		t.Fatalf("expected an empty report, got %+v", r)
+	}
+}
@@ -0,0 +1,125 @@
+package features
+
+import "testing"
+
+// TestHashingEmbedder проверяет размерность, детерминированность и
+// знаковое хэширование категориальных признаков.
+func TestHashingEmbedder(t *testing.T) {
+	cfg := FeatureConfig{NumNumeric: 2, NumCategorical: 2, HashBuckets: 8}
+	e := NewHashingEmbedder(cfg)
+
+	raw := RawFeatureVector{
+		Numeric:     []float64{0.5, 0.25},
+		Categorical: []string{"prod", "api"},
+	}
+	fv := e.Embed(raw)
+	if len(fv) != e.Dim() {
+		t.Fatalf("expected %d features, got %d", e.Dim(), len(fv))
+	}
+	if fv[0] != 0.5 || fv[1] != 0.25 {
+		t.Fatalf("numeric features must be copied as is: %v", fv)
+	}
+
+	total := 0.0
+	for _, v := range fv[2:] {
+		total += v
+	}
+	if total != 2 {
+		t.Fatalf("expected two unsigned hash hits, got %v", total)
+	}
+
+	again := e.Embed(raw)
+	for i := range fv {
+		if fv[i] != again[i] {
+			t.Fatalf("hashing is not deterministic at index %d", i)
+		}
+	}
+
+	cfg.SignedHash = true
+	signed := NewHashingEmbedder(cfg).Embed(raw)
+	for i := 2; i < len(fv); i++ {
+		if signed[i] != fv[i] && signed[i] != -fv[i] {
+			t.Fatalf("signed hashing must only flip signs: %v vs %v", signed, fv)
+		}
+	}
+}
+
+// TestOneHotEmbedderVocabulary проверяет рост словаря и общий слот
+// "other" после достижения лимита категорий.
+func TestOneHotEmbedderVocabulary(t *testing.T) {
+	cfg := FeatureConfig{NumNumeric: 1, NumCategorical: 1, MaxCategories: 2}
+	e := NewOneHotEmbedder(cfg)
+
+	embed := func(v string) FeatureVector {
+		return e.Embed(RawFeatureVector{Numeric: []float64{1}, Categorical: []string{v}})
+	}
+
+	dev := embed("dev")
+	prod := embed("prod")
+	staging := embed("staging")
+	other := embed("canary")
+
+	if len(dev) != e.Dim() || e.Dim() != 4 {
+		t.Fatalf("expected dimension 4, got %d (Dim=%d)", len(dev), e.Dim())
+	}
+	if dev[1] != 1 || prod[2] != 1 {
+		t.Fatalf("expected dedicated slots for the first values: %v %v", dev, prod)
+	}
+	if staging[3] != 1 || other[3] != 1 {
+		t.Fatalf("expected overflow values in the other slot: %v %v", staging, other)
+	}
+	if again := embed("dev"); again[1] != 1 {
+		t.Fatalf("known value moved to another slot: %v", again)
+	}
+
+	names := e.FeatureNames([]string{"cpu"}, []string{"env"})
+	want := []string{"cpu", "env=dev", "env=prod", "env=<other>"}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("unexpected names %v, want %v", names, want)
+		}
+	}
+}
+
+// TestTargetEncoder проверяет сглаживание к априорной вероятности и
+// использование только ранее наблюдённых меток.
+func TestTargetEncoder(t *testing.T) {
+	e := NewTargetEncoder(FeatureConfig{NumCategorical: 1, TargetSmoothing: 2, TargetMinCount: 3})
+
+	raw := func(v string) RawFeatureVector {
+		return RawFeatureVector{Categorical: []string{v}}
+	}
+
+	// До наблюдений любое значение кодируется априорной вероятностью 0.5.
+	if got := e.Embed(raw("prod"))[0]; got != 0.5 {
+		t.Fatalf("expected prior 0.5 before observations, got %v", got)
+	}
+
+	for i := 0; i < 2; i++ {
+		e.Observe(raw("prod"), true)
+	}
+	// Меньше TargetMinCount наблюдений — используется глобальный prior.
+	prior := (2.0 + 1) / (2.0 + 2)
+	if got := e.Embed(raw("prod"))[0]; got != prior {
+		t.Fatalf("expected prior %v below min count, got %v", prior, got)
+	}
+
+	e.Observe(raw("prod"), true)
+	for i := 0; i < 5; i++ {
+		e.Observe(raw("dev"), false)
+	}
+
+	prior = (3.0 + 1) / (8.0 + 2)
+	wantProd := (3 + 2*prior) / (3 + 2)
+	wantDev := (0 + 2*prior) / (5 + 2)
+	fv := e.Embed(raw("prod"))
+	if fv[0] != wantProd {
+		t.Fatalf("expected prod encoding %v, got %v", wantProd, fv[0])
+	}
+	if got := e.Embed(raw("dev"))[0]; got != wantDev {
+		t.Fatalf("expected dev encoding %v, got %v", wantDev, got)
+	}
+	if got := e.Embed(raw("unseen"))[0]; got != prior {
+		t.Fatalf("expected unseen value to use prior %v, got %v", prior, got)
+	}
+}
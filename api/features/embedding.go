@@ -29,6 +29,27 @@ type EmbedderFactory interface {
 	NewEmbedder(cfg FeatureConfig) Embedder
 }
 
+// DimensionedEmbedder is implemented by embedders whose output length is
+// known up front. Predictor uses it to default NumFeatures.
+type DimensionedEmbedder interface {
+	Embedder
+	Dim() int
+}
+
+// FeatureNamer is implemented by embedders that can name their output
+// features given the names of the numeric and categorical input columns.
+type FeatureNamer interface {
+	FeatureNames(numeric, categorical []string) []string
+}
+
+// LabelObserver is implemented by supervised embedders that learn from the
+// labels passed to Predictor.UpdateRaw / Predictor.UpdateRecord. Observe is
+// called after the sample has been embedded and used for training, so the
+// embedding of a sample never depends on its own label.
+type LabelObserver interface {
+	Observe(raw RawFeatureVector, label bool)
+}
+
 // IdentityEmbedder is a simple implementation that assumes raw numeric
 // features are already in the desired embedded space and simply copies them.
 type IdentityEmbedder struct{}
@@ -40,6 +61,10 @@ func (IdentityEmbedder) Embed(raw RawFeatureVector) FeatureVector {
 // EmbedFeatures is a helper used by examples to turn simple maps of numeric
 // and categorical features into a dense FeatureVector.
 //
+// The layout depends on the set of keys present in the maps, so adding a new
+// key shifts the positions of existing features. Use Schema when the inputs
+// are not guaranteed to always carry the same keys.
+//
 // The current strategy is:
 //   - sort numeric feature names and append their values in that order
 //   - sort categorical feature names and encode each categorical value into
@@ -71,13 +96,17 @@ func EmbedFeatures(numeric map[string]float64, categorical map[string]string) Fe
 	}
 	sort.Strings(catKeys)
 	for _, k := range catKeys {
-		v := categorical[k]
-		h := fnv.New64a()
-		_, _ = h.Write([]byte(v))
-		hashVal := h.Sum64()
-		const denom = float64(^uint64(0))
-		values = append(values, float64(hashVal)/denom)
+		values = append(values, hashUnit(categorical[k]))
 	}
 
 	return values
 }
+
+// hashUnit deterministically maps a string into [0,1).
+func hashUnit(v string) float64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(v))
+	hashVal := h.Sum64()
+	const denom = float64(^uint64(0))
+	return float64(hashVal) / denom
+}
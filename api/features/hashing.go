@@ -0,0 +1,72 @@
+package features
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+)
+
+const defaultHashBuckets = 64
+
+// HashingEmbedder implements the hashing trick for categorical columns.
+// Numeric values are copied as is, followed by HashBuckets slots shared by
+// all categorical columns. Each (column, value) pair increments the slot it
+// hashes to, or adds ±1 when SignedHash is enabled.
+//
+// The output length is len(raw.Numeric) + HashBuckets. HashingEmbedder is
+// stateless and safe for concurrent use.
+type HashingEmbedder struct {
+	cfg FeatureConfig
+}
+
+// NewHashingEmbedder creates a HashingEmbedder from cfg.
+func NewHashingEmbedder(cfg FeatureConfig) *HashingEmbedder {
+	if cfg.HashBuckets <= 0 {
+		cfg.HashBuckets = defaultHashBuckets
+	}
+	return &HashingEmbedder{cfg: cfg}
+}
+
+// Dim returns the output length for cfg.NumNumeric numeric columns.
+func (e *HashingEmbedder) Dim() int {
+	return e.cfg.NumNumeric + e.cfg.HashBuckets
+}
+
+func (e *HashingEmbedder) Embed(raw RawFeatureVector) FeatureVector {
+	offset := len(raw.Numeric)
+	fv := make(FeatureVector, offset+e.cfg.HashBuckets)
+	copy(fv, raw.Numeric)
+
+	for col, v := range raw.Categorical {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(strconv.Itoa(col)))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(v))
+		sum := h.Sum64()
+
+		bucket := int(sum % uint64(e.cfg.HashBuckets))
+		sign := 1.0
+		if e.cfg.SignedHash && sum>>63 == 1 {
+			sign = -1.0
+		}
+		fv[offset+bucket] += sign
+	}
+	return fv
+}
+
+// FeatureNames names numeric columns after their inputs and hash buckets
+// as "hash[i]".
+func (e *HashingEmbedder) FeatureNames(numeric, _ []string) []string {
+	names := append([]string(nil), numeric...)
+	for i := 0; i < e.cfg.HashBuckets; i++ {
+		names = append(names, fmt.Sprintf("hash[%d]", i))
+	}
+	return names
+}
+
+// HashingEmbedderFactory creates HashingEmbedder instances.
+type HashingEmbedderFactory struct{}
+
+func (HashingEmbedderFactory) NewEmbedder(cfg FeatureConfig) Embedder {
+	return NewHashingEmbedder(cfg)
+}
@@ -0,0 +1,116 @@
+package features
+
+import (
+	"fmt"
+	"sync"
+)
+
+const defaultMaxCategories = 32
+
+// OneHotEmbedder one-hot encodes categorical columns with a vocabulary that
+// is learned online. Each categorical column owns MaxCategories+1 slots:
+// values get their own slot in order of first appearance until the
+// vocabulary is full, after which new values share the trailing "other" slot.
+//
+// The output length is len(raw.Numeric) + len(raw.Categorical)*(MaxCategories+1).
+// OneHotEmbedder is safe for concurrent use.
+type OneHotEmbedder struct {
+	cfg FeatureConfig
+
+	mu     sync.Mutex
+	vocabs []map[string]int
+	values [][]string
+}
+
+// NewOneHotEmbedder creates a OneHotEmbedder from cfg.
+func NewOneHotEmbedder(cfg FeatureConfig) *OneHotEmbedder {
+	if cfg.MaxCategories <= 0 {
+		cfg.MaxCategories = defaultMaxCategories
+	}
+	return &OneHotEmbedder{cfg: cfg}
+}
+
+// Dim returns the output length for the input layout in the config.
+func (e *OneHotEmbedder) Dim() int {
+	return e.cfg.NumNumeric + e.cfg.NumCategorical*(e.cfg.MaxCategories+1)
+}
+
+func (e *OneHotEmbedder) Embed(raw RawFeatureVector) FeatureVector {
+	width := e.cfg.MaxCategories + 1
+	offset := len(raw.Numeric)
+	fv := make(FeatureVector, offset+len(raw.Categorical)*width)
+	copy(fv, raw.Numeric)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for col, v := range raw.Categorical {
+		fv[offset+col*width+e.slot(col, v)] = 1
+	}
+	return fv
+}
+
+// slot returns the slot of v in column col, growing the vocabulary if there
+// is room. Callers must hold e.mu.
+func (e *OneHotEmbedder) slot(col int, v string) int {
+	for len(e.vocabs) <= col {
+		e.vocabs = append(e.vocabs, make(map[string]int))
+		e.values = append(e.values, nil)
+	}
+
+	vocab := e.vocabs[col]
+	if i, ok := vocab[v]; ok {
+		return i
+	}
+	if len(vocab) < e.cfg.MaxCategories {
+		i := len(vocab)
+		vocab[v] = i
+		e.values[col] = append(e.values[col], v)
+		return i
+	}
+	return e.cfg.MaxCategories
+}
+
+// Vocabulary returns the values learned so far for categorical column col,
+// in slot order.
+func (e *OneHotEmbedder) Vocabulary(col int) []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if col < 0 || col >= len(e.values) {
+		return nil
+	}
+	return append([]string(nil), e.values[col]...)
+}
+
+// FeatureNames names one-hot slots as "column=value" for learned values,
+// "column#i" for slots not yet assigned and "column=<other>" for the
+// overflow slot.
+func (e *OneHotEmbedder) FeatureNames(numeric, categorical []string) []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	names := append([]string(nil), numeric...)
+	for col, name := range categorical {
+		var learned []string
+		if col < len(e.values) {
+			learned = e.values[col]
+		}
+		for i := 0; i < e.cfg.MaxCategories; i++ {
+			if i < len(learned) {
+				names = append(names, fmt.Sprintf("%s=%s", name, learned[i]))
+			} else {
+				names = append(names, fmt.Sprintf("%s#%d", name, i))
+			}
+		}
+		names = append(names, name+"=<other>")
+	}
+	return names
+}
+
+// OneHotEmbedderFactory creates OneHotEmbedder instances.
+type OneHotEmbedderFactory struct{}
+
+func (OneHotEmbedderFactory) NewEmbedder(cfg FeatureConfig) Embedder {
+	return NewOneHotEmbedder(cfg)
+}
@@ -0,0 +1,292 @@
+package features
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	// ErrMissingColumn is returned when a declared column is absent from an
+	// input record and the schema does not allow defaults.
+	ErrMissingColumn = errors.New("features: missing column")
+	// ErrUnknownColumn is returned when an input record contains a column
+	// that is not declared in the schema and the schema rejects unknowns.
+	ErrUnknownColumn = errors.New("features: unknown column")
+	// ErrColumnType is returned when a value cannot be converted to the
+	// declared column type.
+	ErrColumnType = errors.New("features: invalid column value")
+)
+
+// Column declares a single named input column of a Schema.
+type Column struct {
+	Name string
+	Type FeatureType
+
+	// Default is used when the column is missing from a record and the
+	// schema uses MissingDefault. It must be convertible to the column
+	// type; a nil Default means 0 for numeric and "" for categorical columns.
+	Default any
+}
+
+// NumericColumn declares a numeric column.
+func NumericColumn(name string) Column {
+	return Column{Name: name, Type: FeatureNumeric}
+}
+
+// CategoricalColumn declares a categorical column.
+func CategoricalColumn(name string) Column {
+	return Column{Name: name, Type: FeatureCategorical}
+}
+
+// MissingPolicy controls how a Schema handles declared columns that are
+// absent from an input record or present with a nil value.
+type MissingPolicy int
+
+const (
+	// MissingReject returns ErrMissingColumn.
+	MissingReject MissingPolicy = iota
+	// MissingDefault substitutes the column's Default value.
+	MissingDefault
+)
+
+// UnknownPolicy controls how a Schema handles record keys that are not
+// declared as columns.
+type UnknownPolicy int
+
+const (
+	// UnknownReject returns ErrUnknownColumn.
+	UnknownReject UnknownPolicy = iota
+	// UnknownIgnore silently drops undeclared keys.
+	UnknownIgnore
+)
+
+// Schema declares the named input columns of a model once and maps records
+// (map[string]any) to feature vectors in a fixed column order. Unlike
+// EmbedFeatures, the layout never changes when a record gains or loses keys.
+//
+// A Schema is immutable after construction (except for the policy fields,
+// which should be set before use) and safe for concurrent use. It is usually
+// built with NewSchema, which validates the columns and indexes them by
+// name; a Schema built as a struct literal also works but looks columns up
+// by a linear scan and does not validate them.
+//
+// Example:
+//
+//	schema, err := features.NewSchema([]features.Column{
+//		features.NumericColumn("cpu"),
+//		features.NumericColumn("mem"),
+//		features.CategoricalColumn("env"),
+//	})
+//	fv, err := schema.Embed(map[string]any{"cpu": 0.7, "mem": 0.4, "env": "prod"})
+type Schema struct {
+	Columns []Column
+
+	OnMissing MissingPolicy
+	OnUnknown UnknownPolicy
+
+	index map[string]int
+}
+
+// NewSchema validates the columns and builds a Schema. Column names must be
+// non-empty and unique.
+func NewSchema(columns []Column) (*Schema, error) {
+	s := &Schema{
+		Columns: append([]Column(nil), columns...),
+		index:   make(map[string]int, len(columns)),
+	}
+	for i, c := range s.Columns {
+		if c.Name == "" {
+			return nil, fmt.Errorf("features: column %d has empty name", i)
+		}
+		if _, dup := s.index[c.Name]; dup {
+			return nil, fmt.Errorf("features: duplicate column %q", c.Name)
+		}
+		if c.Type != FeatureNumeric && c.Type != FeatureCategorical {
+			return nil, fmt.Errorf("features: column %q has unsupported type %d", c.Name, c.Type)
+		}
+		s.index[c.Name] = i
+	}
+	return s, nil
+}
+
+// Len returns the number of declared columns.
+func (s *Schema) Len() int {
+	return len(s.Columns)
+}
+
+// Names returns the column names in schema order.
+func (s *Schema) Names() []string {
+	names := make([]string, len(s.Columns))
+	for i, c := range s.Columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// NumericNames returns the names of the numeric columns in schema order.
+func (s *Schema) NumericNames() []string {
+	return s.namesOf(FeatureNumeric)
+}
+
+// CategoricalNames returns the names of the categorical columns in schema
+// order.
+func (s *Schema) CategoricalNames() []string {
+	return s.namesOf(FeatureCategorical)
+}
+
+func (s *Schema) namesOf(t FeatureType) []string {
+	var names []string
+	for _, c := range s.Columns {
+		if c.Type == t {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+// Index returns the position of the named column and whether it exists.
+func (s *Schema) Index(name string) (int, bool) {
+	if s.index == nil {
+		for i, c := range s.Columns {
+			if c.Name == name {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+	i, ok := s.index[name]
+	return i, ok
+}
+
+// Raw converts a record into a RawFeatureVector. A column whose key is
+// present with a nil value is treated as missing.
+//
+// Numeric and Categorical hold the values of the numeric and categorical
+// columns respectively, each in schema order. FeatureTypes[i] is the type of
+// column i, and OriginalIndex[i] is the position of column i's value within
+// Numeric or Categorical (depending on its type).
+func (s *Schema) Raw(record map[string]any) (RawFeatureVector, error) {
+	if err := s.checkUnknown(record); err != nil {
+		return RawFeatureVector{}, err
+	}
+
+	raw := RawFeatureVector{
+		FeatureTypes:  make([]FeatureType, len(s.Columns)),
+		OriginalIndex: make([]int, len(s.Columns)),
+	}
+	for i, c := range s.Columns {
+		v := record[c.Name]
+		if v == nil {
+			if s.OnMissing != MissingDefault {
+				return RawFeatureVector{}, fmt.Errorf("%w %q", ErrMissingColumn, c.Name)
+			}
+			v = c.Default
+		}
+
+		raw.FeatureTypes[i] = c.Type
+		switch c.Type {
+		case FeatureNumeric:
+			f, err := toFloat(v)
+			if err != nil {
+				return RawFeatureVector{}, fmt.Errorf("%w %q: %v", ErrColumnType, c.Name, err)
+			}
+			raw.OriginalIndex[i] = len(raw.Numeric)
+			raw.Numeric = append(raw.Numeric, f)
+		case FeatureCategorical:
+			raw.OriginalIndex[i] = len(raw.Categorical)
+			raw.Categorical = append(raw.Categorical, toCategory(v))
+		}
+	}
+	return raw, nil
+}
+
+// Embed converts a record into a FeatureVector with one value per column in
+// schema order. Numeric values are copied as is and categorical values are
+// hashed into [0,1) as in EmbedFeatures.
+func (s *Schema) Embed(record map[string]any) (FeatureVector, error) {
+	raw, err := s.Raw(record)
+	if err != nil {
+		return nil, err
+	}
+
+	fv := make(FeatureVector, len(s.Columns))
+	for i, t := range raw.FeatureTypes {
+		j := raw.OriginalIndex[i]
+		if t == FeatureNumeric {
+			fv[i] = raw.Numeric[j]
+		} else {
+			fv[i] = hashUnit(raw.Categorical[j])
+		}
+	}
+	return fv, nil
+}
+
+func (s *Schema) checkUnknown(record map[string]any) error {
+	if s.OnUnknown == UnknownIgnore {
+		return nil
+	}
+
+	var unknown []string
+	for k := range record {
+		if _, ok := s.Index(k); !ok {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("%w %q", ErrUnknownColumn, unknown[0])
+}
+
+func toFloat(v any) (float64, error) {
+	switch x := v.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return x, nil
+	case float32:
+		return float64(x), nil
+	case int:
+		return float64(x), nil
+	case int8:
+		return float64(x), nil
+	case int16:
+		return float64(x), nil
+	case int32:
+		return float64(x), nil
+	case int64:
+		return float64(x), nil
+	case uint:
+		return float64(x), nil
+	case uint8:
+		return float64(x), nil
+	case uint16:
+		return float64(x), nil
+	case uint32:
+		return float64(x), nil
+	case uint64:
+		return float64(x), nil
+	case bool:
+		if x {
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("cannot convert %T to float64", v)
+	}
+}
+
+func toCategory(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case fmt.Stringer:
+		return x.String()
+	default:
+		return fmt.Sprint(x)
+	}
+}
@@ -0,0 +1,141 @@
+package features
+
+import (
+	"errors"
+	"testing"
+)
+
+func testSchema(t *testing.T) *Schema {
+	t.Helper()
+	s, err := NewSchema([]Column{
+		NumericColumn("cpu"),
+		NumericColumn("mem"),
+		CategoricalColumn("env"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected schema error: %v", err)
+	}
+	return s
+}
+
+// TestSchemaStableLayout проверяет, что порядок признаков задаётся схемой
+// и не зависит от набора ключей во входной записи.
+func TestSchemaStableLayout(t *testing.T) {
+	s := testSchema(t)
+	s.OnUnknown = UnknownIgnore
+
+	fv1, err := s.Embed(map[string]any{"cpu": 0.7, "mem": 0.4, "env": "prod"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fv2, err := s.Embed(map[string]any{"cpu": 0.7, "mem": 0.4, "env": "prod", "aaa": 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(fv1) != 3 || len(fv2) != 3 {
+		t.Fatalf("expected 3 features, got %d and %d", len(fv1), len(fv2))
+	}
+	for i := range fv1 {
+		if fv1[i] != fv2[i] {
+			t.Fatalf("layout changed at index %d: %v vs %v", i, fv1[i], fv2[i])
+		}
+	}
+	if fv1[0] != 0.7 || fv1[1] != 0.4 {
+		t.Fatalf("numeric columns out of order: %v", fv1)
+	}
+	if fv1[2] != EmbedFeatures(nil, map[string]string{"env": "prod"})[0] {
+		t.Fatalf("categorical column is not hashed like EmbedFeatures")
+	}
+}
+
+// TestSchemaPolicies проверяет обработку отсутствующих и неизвестных колонок.
+func TestSchemaPolicies(t *testing.T) {
+	s := testSchema(t)
+
+	if _, err := s.Embed(map[string]any{"cpu": 1, "env": "dev"}); !errors.Is(err, ErrMissingColumn) {
+		t.Fatalf("expected ErrMissingColumn, got %v", err)
+	}
+	if _, err := s.Embed(map[string]any{"cpu": 1, "mem": 2, "env": "dev", "x": 3}); !errors.Is(err, ErrUnknownColumn) {
+		t.Fatalf("expected ErrUnknownColumn, got %v", err)
+	}
+	if _, err := s.Embed(map[string]any{"cpu": "high", "mem": 2, "env": "dev"}); !errors.Is(err, ErrColumnType) {
+		t.Fatalf("expected ErrColumnType, got %v", err)
+	}
+
+	s.Columns[1].Default = 0.5
+	s.OnMissing = MissingDefault
+	fv, err := s.Embed(map[string]any{"cpu": 1, "env": "dev"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fv[1] != 0.5 {
+		t.Fatalf("expected default 0.5 for missing column, got %v", fv[1])
+	}
+}
+
+// TestSchemaRaw проверяет раскладку RawFeatureVector по типам колонок.
+func TestSchemaRaw(t *testing.T) {
+	s := testSchema(t)
+
+	raw, err := s.Raw(map[string]any{"cpu": 0.1, "mem": 0.2, "env": "staging"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(raw.Numeric) != 2 || len(raw.Categorical) != 1 {
+		t.Fatalf("unexpected raw layout: %+v", raw)
+	}
+	if raw.FeatureTypes[2] != FeatureCategorical || raw.Categorical[raw.OriginalIndex[2]] != "staging" {
+		t.Fatalf("categorical column not mapped correctly: %+v", raw)
+	}
+}
+
+// TestNewSchemaDuplicate проверяет, что дубликаты имён колонок отклоняются.
+func TestNewSchemaDuplicate(t *testing.T) {
+	if _, err := NewSchema([]Column{NumericColumn("a"), CategoricalColumn("a")}); err == nil {
+		t.Fatalf("expected error for duplicate column names")
+	}
+}
+
+// TestSchemaNilValue проверяет, что явный nil считается отсутствующим
+// значением колонки.
+func TestSchemaNilValue(t *testing.T) {
+	s := testSchema(t)
+
+	if _, err := s.Embed(map[string]any{"cpu": 1, "mem": nil, "env": "dev"}); !errors.Is(err, ErrMissingColumn) {
+		t.Fatalf("expected ErrMissingColumn for nil numeric value, got %v", err)
+	}
+	if _, err := s.Embed(map[string]any{"cpu": 1, "mem": 2, "env": nil}); !errors.Is(err, ErrMissingColumn) {
+		t.Fatalf("expected ErrMissingColumn for nil categorical value, got %v", err)
+	}
+
+	s.Columns[1].Default = 0.5
+	s.OnMissing = MissingDefault
+	fv, err := s.Embed(map[string]any{"cpu": 1, "mem": nil, "env": "dev"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fv[1] != 0.5 {
+		t.Fatalf("expected default 0.5 for nil value, got %v", fv[1])
+	}
+}
+
+// TestSchemaLiteral проверяет, что схема, заданная литералом структуры,
+// находит свои колонки.
+func TestSchemaLiteral(t *testing.T) {
+	s := &Schema{Columns: []Column{NumericColumn("cpu"), CategoricalColumn("env")}}
+
+	fv, err := s.Embed(map[string]any{"cpu": 0.3, "env": "prod"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fv[0] != 0.3 {
+		t.Fatalf("expected cpu 0.3, got %v", fv[0])
+	}
+	if i, ok := s.Index("env"); !ok || i != 1 {
+		t.Fatalf("expected env at index 1, got %d, %v", i, ok)
+	}
+	if _, err := s.Embed(map[string]any{"cpu": 0.3, "env": "prod", "x": 1}); !errors.Is(err, ErrUnknownColumn) {
+		t.Fatalf("expected ErrUnknownColumn, got %v", err)
+	}
+}
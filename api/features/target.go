@@ -0,0 +1,128 @@
+package features
+
+import "sync"
+
+const (
+	defaultTargetSmoothing = 10.0
+	defaultTargetMinCount  = 1
+)
+
+// categoryStats counts labeled observations of a single category value.
+type categoryStats struct {
+	pos float64
+	n   float64
+}
+
+// TargetEncoder replaces each categorical value with a smoothed estimate of
+// the positive rate observed for it so far:
+//
+//	enc = (pos + m*prior) / (n + m)
+//
+// where prior is the global positive rate and m is TargetSmoothing. Values
+// seen fewer than TargetMinCount times are encoded as the prior.
+//
+// Statistics are updated only through Observe, which Predictor calls after
+// training on a sample, so a sample's own label never leaks into its
+// encoding. The output length is len(raw.Numeric) + len(raw.Categorical).
+// TargetEncoder is safe for concurrent use.
+type TargetEncoder struct {
+	cfg FeatureConfig
+
+	mu     sync.RWMutex
+	pos    float64
+	n      float64
+	counts []map[string]*categoryStats
+}
+
+// NewTargetEncoder creates a TargetEncoder from cfg.
+func NewTargetEncoder(cfg FeatureConfig) *TargetEncoder {
+	if cfg.TargetSmoothing <= 0 {
+		cfg.TargetSmoothing = defaultTargetSmoothing
+	}
+	if cfg.TargetMinCount <= 0 {
+		cfg.TargetMinCount = defaultTargetMinCount
+	}
+	return &TargetEncoder{cfg: cfg}
+}
+
+// Dim returns the output length for the input layout in the config.
+func (e *TargetEncoder) Dim() int {
+	return e.cfg.NumNumeric + e.cfg.NumCategorical
+}
+
+func (e *TargetEncoder) Embed(raw RawFeatureVector) FeatureVector {
+	offset := len(raw.Numeric)
+	fv := make(FeatureVector, offset+len(raw.Categorical))
+	copy(fv, raw.Numeric)
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	prior := e.prior()
+	for col, v := range raw.Categorical {
+		fv[offset+col] = e.encode(col, v, prior)
+	}
+	return fv
+}
+
+// Observe updates the per-category statistics with a labeled sample.
+func (e *TargetEncoder) Observe(raw RawFeatureVector, label bool) {
+	y := 0.0
+	if label {
+		y = 1.0
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.pos += y
+	e.n++
+	for len(e.counts) < len(raw.Categorical) {
+		e.counts = append(e.counts, make(map[string]*categoryStats))
+	}
+	for col, v := range raw.Categorical {
+		cs, ok := e.counts[col][v]
+		if !ok {
+			cs = &categoryStats{}
+			e.counts[col][v] = cs
+		}
+		cs.pos += y
+		cs.n++
+	}
+}
+
+// prior returns the Laplace-smoothed global positive rate. Callers must hold
+// e.mu.
+func (e *TargetEncoder) prior() float64 {
+	return (e.pos + 1) / (e.n + 2)
+}
+
+// encode returns the smoothed rate of v in column col. Callers must hold e.mu.
+func (e *TargetEncoder) encode(col int, v string, prior float64) float64 {
+	if col >= len(e.counts) {
+		return prior
+	}
+	cs, ok := e.counts[col][v]
+	if !ok || cs.n < float64(e.cfg.TargetMinCount) {
+		return prior
+	}
+	m := e.cfg.TargetSmoothing
+	return (cs.pos + m*prior) / (cs.n + m)
+}
+
+// FeatureNames names numeric columns after their inputs and encoded
+// categorical columns as "column:target".
+func (e *TargetEncoder) FeatureNames(numeric, categorical []string) []string {
+	names := append([]string(nil), numeric...)
+	for _, name := range categorical {
+		names = append(names, name+":target")
+	}
+	return names
+}
+
+// TargetEncoderFactory creates TargetEncoder instances.
+type TargetEncoderFactory struct{}
+
+func (TargetEncoderFactory) NewEmbedder(cfg FeatureConfig) Embedder {
+	return NewTargetEncoder(cfg)
+}
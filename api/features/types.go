@@ -8,6 +8,35 @@ type FeatureConfig struct {
 	// (for example, via an online mean-variance normalizer) before being
 	// passed into the forest.
 	NormalizeNumeric bool
+
+	// NumNumeric and NumCategorical describe the raw input layout, i.e. the
+	// number of numeric and categorical columns in a RawFeatureVector. The
+	// built-in embedders use them to report their output dimension. When a
+	// Predictor has a Schema and both are zero, they are filled from it.
+	NumNumeric     int
+	NumCategorical int
+
+	// HashBuckets is the number of buckets used by HashingEmbedder for all
+	// categorical columns combined. Defaults to 64.
+	HashBuckets int
+
+	// SignedHash makes HashingEmbedder add +1 or -1 depending on a second
+	// hash bit, so that collisions cancel out in expectation.
+	SignedHash bool
+
+	// MaxCategories bounds the vocabulary size per categorical column for
+	// OneHotEmbedder. Values beyond it share an extra "other" slot.
+	// Defaults to 32.
+	MaxCategories int
+
+	// TargetSmoothing is the weight of the global positive rate when
+	// TargetEncoder shrinks per-category rates towards it. Defaults to 10.
+	TargetSmoothing float64
+
+	// TargetMinCount is the number of labeled observations a category needs
+	// before TargetEncoder uses its own rate instead of the global prior.
+	// Defaults to 1.
+	TargetMinCount int
 }
 
 // FeatureType describes the semantic type of a single feature in the raw
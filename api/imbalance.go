@@ -0,0 +1,182 @@
+package onlinerf
+
+import (
+	"github.com/kudmo/onlinerf/api/features"
+	"github.com/kudmo/onlinerf/internal/forest"
+)
+
+// ImbalanceStrategy selects how a Predictor compensates for skewed class
+// distributions.
+type ImbalanceStrategy int
+
+const (
+	// ImbalanceNone trains every tree on every sample with weight 1.
+	ImbalanceNone ImbalanceStrategy = iota
+	// ImbalanceClassWeight weights each sample by the inverse of its
+	// class's running prior, so both classes carry equal total weight.
+	ImbalanceClassWeight
+	// ImbalanceOverBagging applies Oza-style online bagging where each tree
+	// sees minority samples Poisson(n_majority/n_minority) times and
+	// majority samples Poisson(1) times.
+	ImbalanceOverBagging
+	// ImbalanceUnderBagging applies Oza-style online bagging where each
+	// tree sees majority samples Poisson(n_minority/n_majority) times and
+	// minority samples Poisson(1) times.
+	ImbalanceUnderBagging
+)
+
+// ImbalanceConfig configures class-imbalance handling and the decision
+// threshold of a Predictor.
+type ImbalanceConfig struct {
+	// Strategy selects the re-weighting / re-sampling scheme.
+	Strategy ImbalanceStrategy
+
+	// DecisionThreshold is the score at or above which Classify returns
+	// true and prequential metrics count a positive prediction.
+	// Defaults to 0.5.
+	DecisionThreshold float64
+
+	// TrackMetrics enables prequential (predict-then-train) confusion
+	// counts, available through Predictor.Metrics. It costs one extra
+	// forest prediction per Update.
+	TrackMetrics bool
+
+	// MetricsHook, if set, is called after every Update with the current
+	// prequential metrics (it implies TrackMetrics). It is called without
+	// holding the predictor lock, so it may call back into the Predictor.
+	MetricsHook func(ClassificationMetrics)
+}
+
+// ClassificationMetrics are prequential confusion counts: every sample is
+// first classified by the model and only then used for training.
+type ClassificationMetrics struct {
+	TP int
+	FP int
+	TN int
+	FN int
+}
+
+func (m *ClassificationMetrics) add(predicted, label bool) {
+	switch {
+	case predicted && label:
+		m.TP++
+	case predicted && !label:
+		m.FP++
+	case !predicted && !label:
+		m.TN++
+	default:
+		m.FN++
+	}
+}
+
+// Total returns the number of evaluated samples.
+func (m ClassificationMetrics) Total() int {
+	return m.TP + m.FP + m.TN + m.FN
+}
+
+// Recall returns the recall of the positive class, TP / (TP + FN).
+func (m ClassificationMetrics) Recall() float64 {
+	return ratio(m.TP, m.TP+m.FN)
+}
+
+// Specificity returns the recall of the negative class, TN / (TN + FP).
+func (m ClassificationMetrics) Specificity() float64 {
+	return ratio(m.TN, m.TN+m.FP)
+}
+
+// Precision returns TP / (TP + FP).
+func (m ClassificationMetrics) Precision() float64 {
+	return ratio(m.TP, m.TP+m.FP)
+}
+
+// MinorityRecall returns the recall of whichever class has been observed
+// less often so far.
+func (m ClassificationMetrics) MinorityRecall() float64 {
+	if m.TP+m.FN <= m.TN+m.FP {
+		return m.Recall()
+	}
+	return m.Specificity()
+}
+
+// BalancedAccuracy returns the mean of the recalls of both classes.
+func (m ClassificationMetrics) BalancedAccuracy() float64 {
+	return (m.Recall() + m.Specificity()) / 2
+}
+
+func ratio(num, den int) float64 {
+	if den == 0 {
+		return 0
+	}
+	return float64(num) / float64(den)
+}
+
+// Classify reports whether fv belongs to the positive class, i.e. whether
+// Predict(fv) reaches Imbalance.DecisionThreshold.
+func (p *Predictor) Classify(fv features.FeatureVector) bool {
+	return p.Predict(fv) >= p.decisionThreshold()
+}
+
+// Metrics returns the prequential classification metrics collected since
+// the predictor was created or ResetMetrics was called. It is empty unless
+// Imbalance.TrackMetrics or Imbalance.MetricsHook is set.
+func (p *Predictor) Metrics() ClassificationMetrics {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.metrics
+}
+
+// ResetMetrics clears the prequential classification metrics.
+func (p *Predictor) ResetMetrics() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.metrics = ClassificationMetrics{}
+}
+
+func (p *Predictor) decisionThreshold() float64 {
+	if p.cfg.Imbalance.DecisionThreshold > 0 {
+		return p.cfg.Imbalance.DecisionThreshold
+	}
+	return 0.5
+}
+
+func (p *Predictor) trackMetrics() bool {
+	return p.cfg.Imbalance.TrackMetrics || p.cfg.Imbalance.MetricsHook != nil
+}
+
+// observeClass updates the running class priors. Callers must hold p.mu.
+func (p *Predictor) observeClass(label bool) {
+	if label {
+		p.classPos++
+	} else {
+		p.classNeg++
+	}
+}
+
+// sampleWeight returns the weight with which tree t learns a sample of the
+// given class under the configured imbalance strategy. The class priors must
+// already include the sample. Callers must hold p.mu.
+func (p *Predictor) sampleWeight(t *forest.Tree, label bool) float64 {
+	own, other := p.classNeg, p.classPos
+	if label {
+		own, other = p.classPos, p.classNeg
+	}
+
+	switch p.cfg.Imbalance.Strategy {
+	case ImbalanceClassWeight:
+		return (own + other) / (2 * own)
+	case ImbalanceOverBagging:
+		lambda := 1.0
+		if own < other {
+			lambda = other / own
+		}
+		return float64(t.Rand.Poisson(lambda))
+	case ImbalanceUnderBagging:
+		lambda := 1.0
+		if own > other {
+			lambda = other / own
+		}
+		return float64(t.Rand.Poisson(lambda))
+	default:
+		return 1
+	}
+}
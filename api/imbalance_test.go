@@ -0,0 +1,85 @@
+package onlinerf
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+// trainSkewed обучает предиктор на потоке с ~3% положительных примеров
+// и возвращает итоговые prequential-метрики.
+func trainSkewed(t *testing.T, strategy ImbalanceStrategy) ClassificationMetrics {
+	t.Helper()
+	var last ClassificationMetrics
+	pred := mustPredictor(PredictorConfig{
+		NumTrees:    5,
+		NumFeatures: 2,
+		TreeOptions: TreeOptions{
+			MaxDepth:            6,
+			HoeffdingSplitDelta: 0.01,
+			MinSamplesPerLeaf:   20,
+		},
+		Seed: 1,
+		Imbalance: ImbalanceConfig{
+			Strategy:    strategy,
+			MetricsHook: func(m ClassificationMetrics) { last = m },
+		},
+	})
+
+	rng := rand.New(rand.NewSource(17))
+	for i := 0; i < 5000; i++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64()}
+		pred.Update(fv, fv[0] > 0.97 && rng.Float64() < 0.9)
+	}
+	if got := pred.Metrics(); got != last {
+		t.Fatalf("hook metrics %+v differ from Metrics() %+v", last, got)
+	}
+	return last
+}
+
+// TestImbalanceStrategiesImproveMinorityRecall проверяет, что стратегии
+// борьбы с дисбалансом повышают полноту по миноритарному классу.
+func TestImbalanceStrategiesImproveMinorityRecall(t *testing.T) {
+	base := trainSkewed(t, ImbalanceNone)
+	if base.Total() != 5000 {
+		t.Fatalf("expected 5000 evaluated samples, got %d", base.Total())
+	}
+
+	for name, s := range map[string]ImbalanceStrategy{
+		"class-weight":  ImbalanceClassWeight,
+		"over-bagging":  ImbalanceOverBagging,
+		"under-bagging": ImbalanceUnderBagging,
+	} {
+		m := trainSkewed(t, s)
+		t.Logf("%s: minority recall %.3f (baseline %.3f)", name, m.MinorityRecall(), base.MinorityRecall())
+		if m.MinorityRecall() <= base.MinorityRecall() {
+			t.Fatalf("%s: expected minority recall above baseline %.3f, got %.3f",
+				name, base.MinorityRecall(), m.MinorityRecall())
+		}
+	}
+}
+
+// TestClassifyThreshold проверяет применение порога решения в Classify.
+func TestClassifyThreshold(t *testing.T) {
+	cfg := PredictorConfig{NumTrees: 1, NumFeatures: 1, TreeOptions: TreeOptions{MaxDepth: 2, MinSamplesPerLeaf: 100}}
+	fv := features.FeatureVector{0.5}
+
+	cfg.Imbalance.DecisionThreshold = 0.2
+	low := mustPredictor(cfg)
+	cfg.Imbalance.DecisionThreshold = 0.5
+	def := mustPredictor(cfg)
+
+	for i := 0; i < 10; i++ {
+		label := i < 3
+		low.Update(fv, label)
+		def.Update(fv, label)
+	}
+
+	if !low.Classify(fv) {
+		t.Fatalf("expected score 0.3 to be positive with threshold 0.2")
+	}
+	if def.Classify(fv) {
+		t.Fatalf("expected score 0.3 to be negative with the default threshold")
+	}
+}
@@ -0,0 +1,83 @@
+package onlinerf
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+// TestLeafPredictionNaiveBayes проверяет, что наивный Байес разделяет
+// классы уже в корневом листе, когда деревья ещё не успели расщепиться.
+func TestLeafPredictionNaiveBayes(t *testing.T) {
+	newPred := func(mode LeafPrediction) *Predictor {
+		return mustPredictor(PredictorConfig{
+			NumTrees:    1,
+			NumFeatures: 2,
+			TreeOptions: TreeOptions{
+				MaxDepth:            5,
+				HoeffdingSplitDelta: 1e-6,
+				MinSamplesPerLeaf:   1000,
+				LeafPrediction:      mode,
+			},
+		})
+	}
+	mc := newPred(LeafMajorityClass)
+	nb := newPred(LeafNaiveBayes)
+
+	rng := rand.New(rand.NewSource(8))
+	for i := 0; i < 100; i++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64()}
+		label := fv[0] > 0.5
+		mc.Update(fv, label)
+		nb.Update(fv, label)
+	}
+
+	hi := features.FeatureVector{0.9, 0.5}
+	lo := features.FeatureVector{0.1, 0.5}
+	if mc.Predict(hi) != mc.Predict(lo) {
+		t.Fatalf("majority class leaf should not depend on features")
+	}
+	if nb.Predict(hi) <= 0.8 || nb.Predict(lo) >= 0.2 {
+		t.Fatalf("naive Bayes leaf should separate classes, hi=%v lo=%v", nb.Predict(hi), nb.Predict(lo))
+	}
+
+	// Объяснения остаются согласованными с наивным Байесом в листьях.
+	exp := nb.Explain(hi)
+	sum := exp.BaseValue
+	for _, c := range exp.Contributions {
+		sum += c
+	}
+	if math.Abs(sum-exp.Prediction) > 1e-9 {
+		t.Fatalf("base + contributions = %v, prediction = %v", sum, exp.Prediction)
+	}
+}
+
+// TestLeafPredictionNBAdaptive проверяет, что адаптивный режим выбирает
+// наивный Байес, когда тот точнее мажоритарного класса.
+func TestLeafPredictionNBAdaptive(t *testing.T) {
+	pred := mustPredictor(PredictorConfig{
+		NumTrees:    1,
+		NumFeatures: 1,
+		TreeOptions: TreeOptions{
+			MaxDepth:          5,
+			MinSamplesPerLeaf: 1000,
+			LeafPrediction:    LeafNBAdaptive,
+		},
+	})
+
+	rng := rand.New(rand.NewSource(9))
+	for i := 0; i < 200; i++ {
+		fv := features.FeatureVector{rng.Float64()}
+		pred.Update(fv, fv[0] > 0.5)
+	}
+
+	root := pred.trees[0].Root
+	if root.NBCorrect <= root.MCCorrect {
+		t.Fatalf("expected naive Bayes to be more accurate, nb=%v mc=%v", root.NBCorrect, root.MCCorrect)
+	}
+	if p := pred.Predict(features.FeatureVector{0.95}); p <= 0.8 {
+		t.Fatalf("expected adaptive leaf to use naive Bayes, got %v", p)
+	}
+}
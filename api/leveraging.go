@@ -0,0 +1,130 @@
+package onlinerf
+
+import (
+	"github.com/kudmo/onlinerf/api/features"
+	"github.com/kudmo/onlinerf/internal/forest"
+)
+
+// EnsembleMode selects how a Predictor trains its trees.
+type EnsembleMode int
+
+const (
+	// EnsembleBagging trains every tree on every sample, weighted by the
+	// configured ImbalanceStrategy. This is the default.
+	EnsembleBagging EnsembleMode = iota
+	// EnsembleLeveraging uses Leveraging Bagging (Bifet et al., 2010):
+	// Poisson(λ) resampling with a large λ, output-code randomization and
+	// an ADWIN detector per tree that resets the worst tree on change.
+	EnsembleLeveraging
+	// EnsembleRandomPatches uses Streaming Random Patches (Gomes et al.,
+	// 2019): each tree is trained on its own random feature subset with
+	// Poisson(λ) online bagging, and per-tree warning and drift detectors
+	// start a background tree and swap it in on drift.
+	EnsembleRandomPatches
+)
+
+// LeveragingConfig configures EnsembleLeveraging.
+type LeveragingConfig struct {
+	// Lambda is the mean of the Poisson distribution used to weight each
+	// sample per tree. Defaults to 6.
+	Lambda float64
+
+	// OutputCodes randomizes the output of each tree: a tree assigned the
+	// flipped code is trained on inverted labels and its prediction is
+	// inverted back, which decorrelates the ensemble.
+	OutputCodes bool
+
+	// DriftAlpha is the significance level of the per-tree ADWIN detectors
+	// watching each tree's error. Defaults to 0.002.
+	DriftAlpha float64
+
+	// DriftWindow bounds the number of recent errors kept by each detector.
+	// Defaults to 1000.
+	DriftWindow int
+}
+
+func (c LeveragingConfig) withDefaults() LeveragingConfig {
+	if c.Lambda <= 0 {
+		c.Lambda = 6
+	}
+	if c.DriftAlpha <= 0 {
+		c.DriftAlpha = 0.002
+	}
+	if c.DriftWindow <= 0 {
+		c.DriftWindow = 1000
+	}
+	return c
+}
+
+// leveragingMember holds the per-tree state of Leveraging Bagging.
+type leveragingMember struct {
+	// flip is the tree's output code: when set the tree learns the negated
+	// label.
+	flip     bool
+	detector *forest.DriftDetector
+}
+
+// newLeveragingMember draws the output code for t from its own random
+// stream so that runs stay reproducible.
+func (p *Predictor) newLeveragingMember(t *forest.Tree) *leveragingMember {
+	cfg := p.cfg.Leveraging
+	m := &leveragingMember{detector: forest.NewBoundedADWIN(cfg.DriftAlpha, cfg.DriftWindow)}
+	if cfg.OutputCodes {
+		m.flip = t.Rand.Float64() < 0.5
+	}
+	return m
+}
+
+// flipped reports whether tree i uses the flipped output code. Callers must
+// hold p.mu.
+func (p *Predictor) flipped(i int) bool {
+	return p.members != nil && p.members[i].flip
+}
+
+// treePredict returns tree i's estimate of the positive class probability,
+// decoding its output code. Callers must hold p.mu.
+func (p *Predictor) treePredict(i int, embedded features.FeatureVector) float64 {
+	prob := p.trees[i].Predict(embedded)
+	if p.flipped(i) {
+		return 1 - prob
+	}
+	return prob
+}
+
+// updateLeveraging trains the trees with Leveraging Bagging and resets the
+// tree with the highest estimated error when any detector signals a change.
+// Callers must hold p.mu.
+func (p *Predictor) updateLeveraging(embedded features.FeatureVector, label bool) {
+	lambda := p.cfg.Leveraging.Lambda
+
+	// Error estimates are taken before the sample is added: a detector that
+	// signals a change forgets its window, and with it the evidence that
+	// its tree is the one that degraded.
+	errs := make([]float64, len(p.trees))
+	change := false
+	for i, t := range p.trees {
+		if t == nil {
+			continue
+		}
+		m := p.members[i]
+		wrong := (p.treePredict(i, embedded) >= 0.5) != label
+		errs[i] = m.detector.Estimate()
+		if m.detector.Add(wrong) {
+			change = true
+		}
+		t.UpdateWeighted(embedded, label != m.flip, float64(t.Rand.Poisson(lambda)))
+	}
+	if !change {
+		return
+	}
+
+	worst, worstErr := -1, -1.0
+	for i, e := range errs {
+		if p.trees[i] != nil && e > worstErr {
+			worst, worstErr = i, e
+		}
+	}
+	if worst >= 0 {
+		p.resetTree(worst)
+	}
+}
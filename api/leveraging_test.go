@@ -0,0 +1,122 @@
+package onlinerf
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+func newLeveragingPredictor() *Predictor {
+	return mustPredictor(PredictorConfig{
+		NumTrees:    6,
+		NumFeatures: 3,
+		TreeOptions: TreeOptions{
+			MaxDepth:            4,
+			MinSamplesPerLeaf:   20,
+			HoeffdingSplitDelta: 0.01,
+			GracePeriod:         50,
+		},
+		Seed:       11,
+		Ensemble:   EnsembleLeveraging,
+		Leveraging: LeveragingConfig{OutputCodes: true},
+	})
+}
+
+// TestLeveragingOutputCodes проверяет, что деревья с инвертированным
+// кодом декодируются при предсказании и в объяснениях.
+func TestLeveragingOutputCodes(t *testing.T) {
+	pred := newLeveragingPredictor()
+	flipped := 0
+	for i := range pred.trees {
+		if pred.flipped(i) {
+			flipped++
+		}
+	}
+	if flipped == 0 || flipped == len(pred.trees) {
+		t.Fatalf("expected a mix of output codes, got %d of %d flipped", flipped, len(pred.trees))
+	}
+
+	trainSynthetic(pred, 3000, 45)
+	for _, fv := range []features.FeatureVector{{0.9, 0.1, 0.5}, {0.1, 0.9, 0.5}} {
+		want := fv[0] > 0.5
+		if got := pred.Predict(fv) >= 0.5; got != want {
+			t.Fatalf("expected prediction %v for %v, got %v", want, fv, pred.Predict(fv))
+		}
+		exp := pred.Explain(fv)
+		sum := exp.BaseValue
+		for _, c := range exp.Contributions {
+			sum += c
+		}
+		if math.Abs(sum-exp.Prediction) > 1e-9 || math.Abs(exp.Prediction-pred.Predict(fv)) > 1e-9 {
+			t.Fatalf("explanation does not add up: base+phi=%v prediction=%v", sum, exp.Prediction)
+		}
+	}
+}
+
+// TestLeveragingResetsOnDrift проверяет, что после смены понятия детекторы
+// сбрасывают деревья и ансамбль восстанавливает точность.
+func TestLeveragingResetsOnDrift(t *testing.T) {
+	pred := newLeveragingPredictor()
+	trainSynthetic(pred, 3000, 46)
+	before := append(pred.trees[:0:0], pred.trees...)
+
+	rng := rand.New(rand.NewSource(47))
+	for i := 0; i < 4000; i++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64(), rng.Float64()}
+		pred.Update(fv, fv[0] <= 0.5)
+	}
+
+	reset := 0
+	for i := range before {
+		if pred.trees[i] != before[i] {
+			reset++
+		}
+	}
+	if reset == 0 {
+		t.Fatalf("expected drift to reset at least one tree")
+	}
+
+	correct := 0
+	for i := 0; i < 500; i++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64(), rng.Float64()}
+		if (pred.Predict(fv) >= 0.5) == (fv[0] <= 0.5) {
+			correct++
+		}
+	}
+	if acc := float64(correct) / 500; acc < 0.8 {
+		t.Fatalf("expected the ensemble to recover after drift, accuracy %v", acc)
+	}
+}
+
+// TestLeveragingResetsDriftedTree проверяет, что при дрейфе одного дерева
+// сбрасывается именно оно, а не исправное дерево с наибольшей оценкой
+// ошибки в оставшемся окне.
+func TestLeveragingResetsDriftedTree(t *testing.T) {
+	pred := newLeveragingPredictor()
+	trainSynthetic(pred, 3000, 48)
+	before := append(pred.trees[:0:0], pred.trees...)
+
+	// Инвертируем выходной код одного дерева: его ошибка резко растёт, а
+	// остальные деревья продолжают работать как прежде.
+	const drifted = 2
+	pred.members[drifted].flip = !pred.members[drifted].flip
+
+	rng := rand.New(rand.NewSource(49))
+	for i := 0; i < 1000; i++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64(), rng.Float64()}
+		pred.Update(fv, fv[0] > 0.5)
+
+		for j := range before {
+			if pred.trees[j] == before[j] {
+				continue
+			}
+			if j != drifted {
+				t.Fatalf("expected tree %d to be reset first, got tree %d", drifted, j)
+			}
+			return
+		}
+	}
+	t.Fatalf("expected the drifted tree to be reset")
+}
@@ -0,0 +1,163 @@
+package onlinerf
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+	"github.com/kudmo/onlinerf/internal/forest"
+)
+
+// TestLogisticLeavesOnSingleLeaf проверяет, что логистический лист без
+// единого разбиения улавливает линейную границу, а частотный — нет.
+func TestLogisticLeavesOnSingleLeaf(t *testing.T) {
+	accuracy := func(mode LeafPrediction) float64 {
+		pred := mustPredictor(PredictorConfig{
+			NumTrees:    1,
+			NumFeatures: 2,
+			TreeOptions: TreeOptions{
+				MaxDepth:          3,
+				MinSamplesPerLeaf: 1 << 30,
+				LeafPrediction:    mode,
+				LeafLearningRate:  0.5,
+			},
+		})
+		return trainDiagonal(pred, 57)
+	}
+
+	if acc := accuracy(LeafLogistic); acc < 0.9 {
+		t.Fatalf("expected logistic leaf accuracy above 0.9, got %v", acc)
+	}
+	if acc := accuracy(LeafMajorityClass); acc > 0.8 {
+		t.Fatalf("expected majority-class leaf to miss the boundary, got %v", acc)
+	}
+}
+
+// TestLogisticLeavesWarmStart проверяет, что новые листья начинают с копии
+// модели родителя.
+func TestLogisticLeavesWarmStart(t *testing.T) {
+	pred := mustPredictor(PredictorConfig{
+		NumTrees:    1,
+		NumFeatures: 2,
+		TreeOptions: TreeOptions{
+			MaxDepth:            1,
+			MinSamplesPerLeaf:   100,
+			HoeffdingSplitDelta: 0.01,
+			GracePeriod:         100,
+			LeafPrediction:      LeafLogistic,
+		},
+	})
+
+	rng := rand.New(rand.NewSource(58))
+	pred.Update(features.FeatureVector{0.5, 0.5}, false)
+	var parent []float64
+	for i := 0; i < 2000 && pred.trees[0].Root.IsLeaf; i++ {
+		root := pred.trees[0].Root
+		if root.Model != nil {
+			parent = append(parent[:0], root.Model.Weights...)
+		}
+		fv := features.FeatureVector{rng.Float64(), rng.Float64()}
+		pred.Update(fv, fv[0] > 0.5)
+	}
+
+	root := pred.trees[0].Root
+	if root.IsLeaf {
+		t.Fatalf("expected the root to split")
+	}
+	if root.Model != nil {
+		t.Fatalf("expected internal nodes to drop their leaf model")
+	}
+	for _, child := range []*forest.Node{root.Left, root.Right} {
+		if child.Model == nil || child.Model.Weights[0] == 0 {
+			t.Fatalf("expected children to start from the parent's model")
+		}
+		// Родительская модель успела сделать ещё один шаг на примере,
+		// вызвавшем разбиение, поэтому веса близки, но не равны.
+		if d := child.Model.Weights[0] - parent[0]; d > 0.1 || d < -0.1 {
+			t.Fatalf("expected child weights near the parent's %v, got %v", parent, child.Model.Weights)
+		}
+	}
+	if root.Left.Model == root.Right.Model {
+		t.Fatalf("expected children to get independent copies of the model")
+	}
+}
+
+// TestLogisticLeavesEFDT проверяет, что в режиме EFDT внутренний узел
+// продолжает обучать свою модель, и при замене разбиения новые листья, а
+// при обрезке сам узел начинают с обученной модели.
+func TestLogisticLeavesEFDT(t *testing.T) {
+	newPred := func(tie float64) *Predictor {
+		return mustPredictor(PredictorConfig{
+			NumTrees:    1,
+			NumFeatures: 2,
+			TreeOptions: TreeOptions{
+				MaxDepth:            1,
+				MinSamplesPerLeaf:   50,
+				HoeffdingSplitDelta: 1e-4,
+				GracePeriod:         50,
+				TieThreshold:        tie,
+				SplitMode:           SplitEFDT,
+				LeafPrediction:      LeafLogistic,
+			},
+		})
+	}
+	trained := func(m *forest.LinearModel) bool {
+		return m != nil && (m.Weights[0] != 0 || m.Weights[1] != 0)
+	}
+
+	t.Run("replace", func(t *testing.T) {
+		pred := newPred(0)
+		rng := rand.New(rand.NewSource(59))
+		pred.Update(features.FeatureVector{0.5, 0.5}, false)
+		for i := 0; i < 6000; i++ {
+			fv := features.FeatureVector{rng.Float64(), rng.Float64()}
+			before := pred.trees[0].Root.SplitFeature
+			wasLeaf := pred.trees[0].Root.IsLeaf
+			pred.Update(fv, i < 1000 && fv[0] > 0.5 || i >= 1000 && fv[1] > 0.5)
+
+			root := pred.trees[0].Root
+			if wasLeaf || root.IsLeaf || root.SplitFeature == before {
+				continue
+			}
+			if !trained(root.Model) || !trained(root.Left.Model) || !trained(root.Right.Model) {
+				t.Fatalf("expected the replaced split to keep warm-started models")
+			}
+			return
+		}
+		t.Fatalf("expected the root split to be replaced")
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		pred := newPred(0)
+		rng := rand.New(rand.NewSource(60))
+		pred.Update(features.FeatureVector{0.5, 0.5}, false)
+		for i := 0; i < 1000; i++ {
+			fv := features.FeatureVector{rng.Float64(), rng.Float64()}
+			pred.Update(fv, fv[0] > 0.5)
+		}
+		tree := pred.trees[0]
+		if tree.Root.IsLeaf {
+			t.Fatalf("expected the root to split")
+		}
+
+		// Делаем разбиение бесполезным: все примеры узла уходят влево, так
+		// что ни один признак не лучше отказа от разбиения.
+		for _, fs := range tree.Root.FeatureStats {
+			fs.Threshold = 2
+			fs.LeftPos, fs.LeftNeg = fs.LeftPos+fs.RightPos, fs.LeftNeg+fs.RightNeg
+			fs.RightPos, fs.RightNeg = 0, 0
+		}
+		tree.Config.TieThreshold = 1
+
+		for i := 0; i < 100 && !tree.Root.IsLeaf; i++ {
+			fv := features.FeatureVector{rng.Float64(), rng.Float64()}
+			pred.Update(fv, fv[0] > 0.5)
+		}
+		if !tree.Root.IsLeaf {
+			t.Fatalf("expected the root split to be pruned")
+		}
+		if !trained(tree.Root.Model) {
+			t.Fatalf("expected the pruned node to keep its trained model")
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package onlinerf
+
+import (
+	"sync"
+
+	"github.com/kudmo/onlinerf/api/features"
+	"github.com/kudmo/onlinerf/internal/forest"
+	"github.com/kudmo/onlinerf/internal/mondrian"
+)
+
+// MondrianConfig configures a MondrianPredictor.
+type MondrianConfig struct {
+	// NumTrees is the number of Mondrian trees. Defaults to 10.
+	NumTrees int
+
+	// Lifetime is the budget of the Mondrian process; larger values allow
+	// finer partitions. Zero means unlimited: leaves keep splitting as long
+	// as they see mixed labels.
+	Lifetime float64
+
+	// Smoothing is the strength of the hierarchical prior that lets nodes
+	// with few samples borrow their ancestors' estimates. Defaults to 1.
+	Smoothing float64
+
+	// MaxNodesPerTree bounds the size of each tree. Zero means unlimited.
+	MaxNodesPerTree int
+
+	// Seed derives the per-tree random streams.
+	Seed int64
+}
+
+// MondrianPredictor is an online Mondrian forest, an alternative learner
+// to Predictor. Mondrian trees split from the first samples and extend
+// consistently as data arrives, so they need far fewer samples than
+// Hoeffding trees before making useful predictions. Every tree sees every
+// sample; the randomness comes from the Mondrian process itself.
+//
+// A MondrianPredictor is safe for concurrent use from multiple goroutines.
+type MondrianPredictor struct {
+	cfg   MondrianConfig
+	trees []*mondrian.Tree
+
+	mu sync.RWMutex
+}
+
+// NewMondrianPredictor creates an empty Mondrian forest.
+func NewMondrianPredictor(cfg MondrianConfig) *MondrianPredictor {
+	if cfg.NumTrees <= 0 {
+		cfg.NumTrees = 10
+	}
+	if cfg.Smoothing <= 0 {
+		cfg.Smoothing = 1
+	}
+
+	treeCfg := mondrian.Config{
+		Lifetime:  cfg.Lifetime,
+		Smoothing: cfg.Smoothing,
+		MaxNodes:  cfg.MaxNodesPerTree,
+	}
+	m := &MondrianPredictor{cfg: cfg, trees: make([]*mondrian.Tree, cfg.NumTrees)}
+	for i := range m.trees {
+		m.trees[i] = mondrian.NewTree(treeCfg, forest.DeriveSeed(cfg.Seed, i))
+	}
+	return m
+}
+
+// Predict returns the estimated probability of the positive class.
+func (m *MondrianPredictor) Predict(fv features.FeatureVector) float64 {
+	prob, _ := m.PredictWithUncertainty(fv)
+	return prob
+}
+
+// PredictWithUncertainty returns the estimated probability of the positive
+// class together with an uncertainty in [0,1]: the average probability that
+// fv lies in a region the trees have not yet partitioned. Predictions for
+// such inputs are drawn towards the estimates of coarser ancestor nodes, so
+// the probability is also less extreme far from the training data.
+func (m *MondrianPredictor) PredictWithUncertainty(fv features.FeatureVector) (prob, uncertainty float64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, t := range m.trees {
+		p, u := t.Predict(fv)
+		prob += p
+		uncertainty += u
+	}
+	n := float64(len(m.trees))
+	return prob / n, uncertainty / n
+}
+
+// Update trains the forest with a single labeled sample.
+func (m *MondrianPredictor) Update(fv features.FeatureVector, label bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.trees {
+		t.Update(fv, label)
+	}
+}
+
+// NodeCounts returns the number of nodes in every tree.
+func (m *MondrianPredictor) NodeCounts() []int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make([]int, len(m.trees))
+	for i, t := range m.trees {
+		counts[i] = t.NodeCount()
+	}
+	return counts
+}
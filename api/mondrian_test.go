@@ -0,0 +1,111 @@
+package onlinerf
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+// TestMondrianLearnsFromFewSamples проверяет, что лес Мондриана даёт
+// полезные предсказания уже после небольшого числа примеров.
+func TestMondrianLearnsFromFewSamples(t *testing.T) {
+	m := NewMondrianPredictor(MondrianConfig{NumTrees: 10, Seed: 3})
+	rng := rand.New(rand.NewSource(50))
+	label := func(fv features.FeatureVector) bool { return fv[0]+fv[1] > 1 }
+
+	for i := 0; i < 200; i++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64()}
+		m.Update(fv, label(fv))
+	}
+
+	correct := 0
+	for i := 0; i < 1000; i++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64()}
+		if (m.Predict(fv) >= 0.5) == label(fv) {
+			correct++
+		}
+	}
+	if acc := float64(correct) / 1000; acc < 0.85 {
+		t.Fatalf("expected accuracy above 0.85 after 200 samples, got %v", acc)
+	}
+}
+
+// TestMondrianUncertainty проверяет, что вдали от обучающих данных
+// неопределённость растёт, а вероятность стремится к априорной.
+func TestMondrianUncertainty(t *testing.T) {
+	m := NewMondrianPredictor(MondrianConfig{NumTrees: 10, Seed: 4})
+	if p, u := m.PredictWithUncertainty(features.FeatureVector{0, 0}); p != 0.5 || u != 1 {
+		t.Fatalf("expected prior prediction for an empty forest, got %v, %v", p, u)
+	}
+
+	rng := rand.New(rand.NewSource(51))
+	for i := 0; i < 500; i++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64()}
+		m.Update(fv, fv[0] > 0.5)
+	}
+
+	pNear, uNear := m.PredictWithUncertainty(features.FeatureVector{0.9, 0.5})
+	pFar, uFar := m.PredictWithUncertainty(features.FeatureVector{50, 0.5})
+	if uNear > 0.1 || uFar < 0.9 {
+		t.Fatalf("expected low uncertainty inside the data and high far away, got %v and %v", uNear, uFar)
+	}
+	if pNear < 0.9 || pFar > pNear || pFar < 0.5 {
+		t.Fatalf("expected far predictions to fall back towards ancestors, near=%v far=%v", pNear, pFar)
+	}
+}
+
+// TestMondrianMaxNodes проверяет ограничение размера деревьев.
+func TestMondrianMaxNodes(t *testing.T) {
+	m := NewMondrianPredictor(MondrianConfig{NumTrees: 3, MaxNodesPerTree: 31, Seed: 5})
+	rng := rand.New(rand.NewSource(52))
+	for i := 0; i < 2000; i++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64()}
+		m.Update(fv, rng.Float64() < 0.5)
+	}
+	for i, n := range m.NodeCounts() {
+		if n > 31 {
+			t.Fatalf("tree %d has %d nodes, expected at most 31", i, n)
+		}
+	}
+}
+
+// TestMondrianResumesPausedLeaf проверяет, что чистый лист, который затем
+// получает обе метки, снова начинает делиться и выучивает границу.
+func TestMondrianResumesPausedLeaf(t *testing.T) {
+	m := NewMondrianPredictor(MondrianConfig{NumTrees: 5, Seed: 6})
+	rng := rand.New(rand.NewSource(53))
+
+	// Пока все метки одинаковы, листья приостановлены и дерево не растёт.
+	for i := 0; i < 100; i++ {
+		m.Update(features.FeatureVector{rng.Float64(), rng.Float64()}, true)
+	}
+	for i, n := range m.NodeCounts() {
+		if n != 1 {
+			t.Fatalf("expected tree %d to stay a single paused leaf, got %d nodes", i, n)
+		}
+	}
+
+	// Новые примеры лежат внутри уже известной области.
+	label := func(fv features.FeatureVector) bool { return fv[0] > 0.5 }
+	for i := 0; i < 500; i++ {
+		fv := features.FeatureVector{0.05 + 0.9*rng.Float64(), 0.05 + 0.9*rng.Float64()}
+		m.Update(fv, label(fv))
+	}
+	for i, n := range m.NodeCounts() {
+		if n == 1 {
+			t.Fatalf("expected tree %d to split once its leaf became mixed", i)
+		}
+	}
+
+	correct := 0
+	for i := 0; i < 1000; i++ {
+		fv := features.FeatureVector{0.05 + 0.9*rng.Float64(), 0.05 + 0.9*rng.Float64()}
+		if (m.Predict(fv) >= 0.5) == label(fv) {
+			correct++
+		}
+	}
+	if acc := float64(correct) / 1000; acc < 0.9 {
+		t.Fatalf("expected accuracy above 0.9, got %v", acc)
+	}
+}
@@ -0,0 +1,124 @@
+package onlinerf
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+// trainDiagonal обучает модель на правиле cpu+mem > 0.7 и возвращает
+// точность на отложенной выборке.
+func trainDiagonal(pred *Predictor, seed int64) float64 {
+	rng := rand.New(rand.NewSource(seed))
+	label := func(fv features.FeatureVector) bool { return fv[0]+fv[1] > 0.7 }
+	for i := 0; i < 5000; i++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64()}
+		pred.Update(fv, label(fv))
+	}
+	correct := 0
+	for i := 0; i < 1000; i++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64()}
+		if (pred.Predict(fv) >= 0.5) == label(fv) {
+			correct++
+		}
+	}
+	return float64(correct) / 1000
+}
+
+func diagonalConfig(oblique bool) PredictorConfig {
+	return PredictorConfig{
+		NumTrees:    1,
+		NumFeatures: 2,
+		TreeOptions: TreeOptions{
+			MaxDepth:            1,
+			MinSamplesPerLeaf:   100,
+			HoeffdingSplitDelta: 0.01,
+			GracePeriod:         100,
+			ObliqueSplits:       oblique,
+			ObliqueLearningRate: 0.5,
+		},
+	}
+}
+
+// TestObliqueSplitOnDiagonalBoundary проверяет, что на диагональной
+// границе лист выбирает наклонное разбиение и одно такое разбиение
+// точнее осевого.
+func TestObliqueSplitOnDiagonalBoundary(t *testing.T) {
+	axis := mustPredictor(diagonalConfig(false))
+	axisAcc := trainDiagonal(axis, 55)
+
+	oblique := mustPredictor(diagonalConfig(true))
+	obliqueAcc := trainDiagonal(oblique, 55)
+
+	root := oblique.trees[0].Root
+	if root.IsLeaf || root.SplitFeature != ObliqueFeature {
+		t.Fatalf("expected an oblique root split, got leaf=%v feature=%d", root.IsLeaf, root.SplitFeature)
+	}
+	if obliqueAcc < 0.9 || obliqueAcc <= axisAcc {
+		t.Fatalf("expected oblique split to beat axis-aligned, got %v vs %v", obliqueAcc, axisAcc)
+	}
+}
+
+// TestObliqueSplitIntrospection проверяет объяснения, пути решений и
+// экспорт для наклонных разбиений.
+func TestObliqueSplitIntrospection(t *testing.T) {
+	cfg := diagonalConfig(true)
+	schema, err := features.NewSchema([]features.Column{
+		features.NumericColumn("cpu"),
+		features.NumericColumn("mem"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected schema error: %v", err)
+	}
+	cfg.Schema = schema
+	pred := mustPredictor(cfg)
+	trainDiagonal(pred, 56)
+
+	fv := features.FeatureVector{0.6, 0.5}
+	exp := pred.Explain(fv)
+	sum := exp.BaseValue
+	for _, c := range exp.Contributions {
+		sum += c
+	}
+	if math.Abs(sum-exp.Prediction) > 1e-9 {
+		t.Fatalf("contributions do not add up: %v vs %v", sum, exp.Prediction)
+	}
+	if exp.Contributions[0] <= 0 || exp.Contributions[1] <= 0 {
+		t.Fatalf("expected both features of the oblique split to contribute, got %v", exp.Contributions)
+	}
+
+	step := pred.DecisionPaths(fv)[0].Steps[0]
+	if step.Feature != ObliqueFeature || !strings.Contains(step.Name, "cpu") || !strings.Contains(step.Name, "mem") {
+		t.Fatalf("unexpected oblique step %+v", step)
+	}
+	if step.Direction != DirectionRight || step.Value <= 0 {
+		t.Fatalf("expected the sample to be routed right with a positive margin, got %s", step)
+	}
+
+	var buf bytes.Buffer
+	if err := pred.ExportJSON(&buf, ExportOptions{}); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	var doc struct {
+		Trees []struct {
+			Root struct {
+				Feature int `json:"feature"`
+				Oblique struct {
+					Features []int     `json:"features"`
+					Weights  []float64 `json:"weights"`
+				} `json:"oblique"`
+			} `json:"root"`
+		} `json:"trees"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if r := doc.Trees[0].Root; r.Feature != ObliqueFeature || len(r.Oblique.Weights) != 2 {
+		t.Fatalf("expected the oblique split in the export, got %+v", r)
+	}
+}
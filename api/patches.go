@@ -0,0 +1,142 @@
+package onlinerf
+
+import (
+	"math"
+
+	"github.com/kudmo/onlinerf/api/features"
+	"github.com/kudmo/onlinerf/internal/forest"
+)
+
+// RandomPatchesConfig configures EnsembleRandomPatches.
+type RandomPatchesConfig struct {
+	// SubspaceSize is the fraction of features each tree is trained on.
+	// Each tree draws its own subset once, when it is created. Defaults to
+	// 0.6.
+	SubspaceSize float64
+
+	// Lambda is the mean of the Poisson distribution used for online
+	// bagging. Defaults to 6.
+	Lambda float64
+
+	// WarningAlpha is the significance level of the per-tree warning
+	// detectors; a warning starts training a background tree. Defaults to
+	// 1e-4.
+	WarningAlpha float64
+
+	// DriftAlpha is the significance level of the per-tree drift detectors;
+	// a drift replaces the tree with its background tree. Defaults to 1e-5.
+	DriftAlpha float64
+
+	// DriftWindow bounds the number of recent errors kept by each detector.
+	// Defaults to 1000.
+	DriftWindow int
+}
+
+func (c RandomPatchesConfig) withDefaults() RandomPatchesConfig {
+	if c.SubspaceSize <= 0 || c.SubspaceSize > 1 {
+		c.SubspaceSize = 0.6
+	}
+	if c.Lambda <= 0 {
+		c.Lambda = 6
+	}
+	if c.WarningAlpha <= 0 {
+		c.WarningAlpha = 1e-4
+	}
+	if c.DriftAlpha <= 0 {
+		c.DriftAlpha = 1e-5
+	}
+	if c.DriftWindow <= 0 {
+		c.DriftWindow = 1000
+	}
+	return c
+}
+
+// patchMember holds the per-tree state of Streaming Random Patches.
+type patchMember struct {
+	warning *forest.DriftDetector
+	drift   *forest.DriftDetector
+	// background is trained alongside the tree after a warning and takes
+	// its place on drift.
+	background *forest.Tree
+}
+
+func (p *Predictor) newPatchMember() *patchMember {
+	cfg := p.cfg.RandomPatches
+	return &patchMember{
+		warning: forest.NewBoundedADWIN(cfg.WarningAlpha, cfg.DriftWindow),
+		drift:   forest.NewBoundedADWIN(cfg.DriftAlpha, cfg.DriftWindow),
+	}
+}
+
+// newMemberTree creates an ensemble member. In EnsembleRandomPatches mode
+// the tree gets its own random feature subset drawn from its random stream.
+func (p *Predictor) newMemberTree(cfg forest.TreeConfig) *forest.Tree {
+	t := forest.NewTree(cfg, p.numFeatures)
+	if p.cfg.Ensemble == EnsembleRandomPatches {
+		k := int(math.Round(p.cfg.RandomPatches.SubspaceSize * float64(p.numFeatures)))
+		if k < 1 {
+			k = 1
+		}
+		t.Config.FeatureSubset = t.Rand.Subset(p.numFeatures, k)
+	}
+	return t
+}
+
+// newBackgroundTree creates a fresh member seeded from t's random stream.
+// Callers must hold p.mu.
+func (p *Predictor) newBackgroundTree(t *forest.Tree) *forest.Tree {
+	cfg := p.treeCfg
+	cfg.Seed = t.Rand.Uint64()
+	return p.newMemberTree(cfg)
+}
+
+// updatePatches trains the trees with Streaming Random Patches: online
+// bagging of trees restricted to their feature subsets, with a background
+// tree started on warning and swapped in on drift. Callers must hold p.mu.
+func (p *Predictor) updatePatches(embedded features.FeatureVector, label bool) {
+	lambda := p.cfg.RandomPatches.Lambda
+
+	for i, t := range p.trees {
+		if t == nil {
+			continue
+		}
+		m := p.patches[i]
+		wrong := (t.Predict(embedded) >= 0.5) != label
+
+		if m.warning.Add(wrong) && m.background == nil {
+			m.background = p.newBackgroundTree(t)
+		}
+		if m.drift.Add(wrong) {
+			next := m.background
+			if next == nil {
+				next = p.newBackgroundTree(t)
+			}
+			p.trees[i] = next
+			p.monitors[i] = newTreeMonitor(p.cfg.Replacement)
+			p.patches[i] = p.newPatchMember()
+			t, m = next, p.patches[i]
+		}
+
+		k := float64(t.Rand.Poisson(lambda))
+		t.UpdateWeighted(embedded, label, k)
+		if m.background != nil {
+			m.background.UpdateWeighted(embedded, label, k)
+		}
+	}
+}
+
+// FeatureSubsets returns the feature indices each tree is trained on. A nil
+// entry means the tree uses all features, which is the case outside
+// EnsembleRandomPatches mode.
+func (p *Predictor) FeatureSubsets() [][]int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	subsets := make([][]int, len(p.trees))
+	for i, t := range p.trees {
+		if t != nil && t.Config.FeatureSubset != nil {
+			subsets[i] = append([]int(nil), t.Config.FeatureSubset...)
+		}
+	}
+	return subsets
+}
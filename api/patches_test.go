@@ -0,0 +1,117 @@
+package onlinerf
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+	"github.com/kudmo/onlinerf/internal/forest"
+)
+
+func newPatchesPredictor() *Predictor {
+	return mustPredictor(PredictorConfig{
+		NumTrees:    8,
+		NumFeatures: 10,
+		TreeOptions: TreeOptions{
+			MaxDepth:            4,
+			MinSamplesPerLeaf:   20,
+			HoeffdingSplitDelta: 0.01,
+			GracePeriod:         50,
+		},
+		Seed:     12,
+		Ensemble: EnsembleRandomPatches,
+	})
+}
+
+func wideSample(rng *rand.Rand) features.FeatureVector {
+	fv := make(features.FeatureVector, 10)
+	for i := range fv {
+		fv[i] = rng.Float64()
+	}
+	return fv
+}
+
+// TestRandomPatchesSubsets проверяет, что каждое дерево получает своё
+// фиксированное подмножество признаков и не строит разбиений вне него.
+func TestRandomPatchesSubsets(t *testing.T) {
+	pred := newPatchesPredictor()
+	subsets := pred.FeatureSubsets()
+
+	distinct := map[string]bool{}
+	for i, s := range subsets {
+		if len(s) != 6 {
+			t.Fatalf("tree %d: expected 6 features, got %v", i, s)
+		}
+		distinct[fmt.Sprint(s)] = true
+	}
+	if len(distinct) < 2 {
+		t.Fatalf("expected trees to draw different subsets, got %v", subsets)
+	}
+
+	rng := rand.New(rand.NewSource(48))
+	for i := 0; i < 3000; i++ {
+		fv := wideSample(rng)
+		pred.Update(fv, fv[0]+fv[1] > 1)
+	}
+	for i, tree := range pred.trees {
+		allowed := map[int]bool{}
+		for _, f := range subsets[i] {
+			allowed[f] = true
+		}
+		for _, d := range pathFeatures(tree.Root) {
+			if !allowed[d] {
+				t.Fatalf("tree %d split on feature %d outside its subset %v", i, d, subsets[i])
+			}
+		}
+	}
+	if mustPredictor(PredictorConfig{NumTrees: 1, NumFeatures: 3}).FeatureSubsets()[0] != nil {
+		t.Fatalf("expected no subsets outside random patches mode")
+	}
+}
+
+// TestRandomPatchesRecoversFromDrift проверяет, что после смены понятия
+// деревья заменяются фоновыми и ансамбль восстанавливает точность.
+func TestRandomPatchesRecoversFromDrift(t *testing.T) {
+	pred := newPatchesPredictor()
+	rng := rand.New(rand.NewSource(49))
+	for i := 0; i < 3000; i++ {
+		fv := wideSample(rng)
+		pred.Update(fv, fv[0]+fv[1] > 1)
+	}
+	before := append(pred.trees[:0:0], pred.trees...)
+
+	for i := 0; i < 4000; i++ {
+		fv := wideSample(rng)
+		pred.Update(fv, fv[0]+fv[1] <= 1)
+	}
+
+	replaced := 0
+	for i := range before {
+		if pred.trees[i] != before[i] {
+			replaced++
+		}
+	}
+	if replaced == 0 {
+		t.Fatalf("expected drift to replace at least one tree")
+	}
+
+	correct := 0
+	for i := 0; i < 500; i++ {
+		fv := wideSample(rng)
+		if (pred.Predict(fv) >= 0.5) == (fv[0]+fv[1] <= 1) {
+			correct++
+		}
+	}
+	if acc := float64(correct) / 500; acc < 0.7 {
+		t.Fatalf("expected the ensemble to recover after drift, accuracy %v", acc)
+	}
+}
+
+// pathFeatures returns the split features of all internal nodes below n.
+func pathFeatures(n *forest.Node) []int {
+	if n == nil || n.IsLeaf {
+		return nil
+	}
+	return append(append([]int{n.SplitFeature}, pathFeatures(n.Left)...), pathFeatures(n.Right)...)
+}
@@ -0,0 +1,158 @@
+package onlinerf
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+// Direction is the branch taken at an internal tree node.
+type Direction int
+
+const (
+	// DirectionLeft means the feature value was <= the split threshold.
+	DirectionLeft Direction = iota
+	// DirectionRight means the feature value was > the split threshold.
+	DirectionRight
+)
+
+// String returns the comparison operator corresponding to the direction.
+func (d Direction) String() string {
+	if d == DirectionLeft {
+		return "<="
+	}
+	return ">"
+}
+
+// DecisionStep is one routing decision on the way from the root to a leaf.
+type DecisionStep struct {
+	// Feature is the index of the split feature in the embedded vector,
+	// or ObliqueFeature for oblique splits.
+	Feature int
+	// Name is the schema name of the feature, if known. For oblique splits
+	// it is the split's linear formula, e.g. "0.83*cpu + 0.61*mem - 0.7".
+	Name string
+	// Threshold is the split threshold of the node.
+	Threshold float64
+	// Value is the (normalized) feature value that was compared, or the
+	// value of the linear formula for oblique splits.
+	Value float64
+	// Direction is the branch that was taken.
+	Direction Direction
+}
+
+// String renders the step as a human-readable condition, e.g. "x[2]=0.71 > 0.5"
+// or "cpu=0.71 > 0.5" when the feature name is known.
+func (s DecisionStep) String() string {
+	name := s.Name
+	if name == "" {
+		name = fmt.Sprintf("x[%d]", s.Feature)
+	}
+	return fmt.Sprintf("%s=%.4g %s %.4g", name, s.Value, s.Direction, s.Threshold)
+}
+
+// LeafStats are the label statistics of the leaf a sample was routed to.
+// Counts are sample weights, which equal plain counts unless class
+// weighting or bagging is enabled.
+type LeafStats struct {
+	Pos float64
+	Neg float64
+}
+
+// DecisionPath is the route of a single sample through one tree.
+type DecisionPath struct {
+	// Tree is the index of the tree within the forest.
+	Tree int
+	// Steps are the decisions from the root down to the leaf. It is empty
+	// for trees that consist of a single leaf or have not seen data yet.
+	Steps []DecisionStep
+	// Leaf holds the statistics of the final leaf.
+	Leaf LeafStats
+	// Prediction is the tree's probability of the positive class.
+	Prediction float64
+}
+
+// String renders the path as "cond && cond -> leaf(...)" for logging.
+func (d DecisionPath) String() string {
+	conds := make([]string, len(d.Steps))
+	for i, s := range d.Steps {
+		conds[i] = s.String()
+	}
+	cond := strings.Join(conds, " && ")
+	if cond == "" {
+		cond = "root"
+	}
+	return fmt.Sprintf("tree %d: %s -> leaf(pos=%g neg=%g p=%.3f)",
+		d.Tree, cond, d.Leaf.Pos, d.Leaf.Neg, d.Prediction)
+}
+
+// DecisionPaths returns, for every tree in the forest, the path taken by fv
+// from the root to a leaf together with that leaf's statistics.
+//
+// The feature vector goes through the same pipeline as in Predict, so the
+// reported values are the ones the trees actually compared.
+//
+// Example:
+//
+//	for _, path := range model.DecisionPaths(fv) {
+//		log.Println(path)
+//	}
+func (p *Predictor) DecisionPaths(fv features.FeatureVector) []DecisionPath {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	embedded := fv
+	if p.normalizer != nil {
+		embedded = p.normalizer.Transform(embedded)
+	}
+
+	names := p.FeatureNames()
+	paths := make([]DecisionPath, 0, len(p.trees))
+	for i, t := range p.trees {
+		if t == nil {
+			continue
+		}
+
+		decisions, leaf := t.DecisionPath(embedded)
+		path := DecisionPath{
+			Tree:       i,
+			Steps:      make([]DecisionStep, len(decisions)),
+			Prediction: p.treePredict(i, embedded),
+		}
+		for j, d := range decisions {
+			dir := DirectionRight
+			if d.Left {
+				dir = DirectionLeft
+			}
+			name := featureName(names, d.Feature)
+			if d.Oblique != nil {
+				name = d.Oblique.Expression(names)
+			}
+			path.Steps[j] = DecisionStep{
+				Feature:   d.Feature,
+				Name:      name,
+				Threshold: d.Threshold,
+				Value:     d.Value,
+				Direction: dir,
+			}
+		}
+		if leaf != nil {
+			path.Leaf = LeafStats{Pos: leaf.Stats.Pos, Neg: leaf.Stats.Neg}
+			if p.flipped(i) {
+				// The tree counts negated labels.
+				path.Leaf.Pos, path.Leaf.Neg = path.Leaf.Neg, path.Leaf.Pos
+			}
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// featureName returns names[i], or "" if it is not available.
+func featureName(names []string, i int) string {
+	if i >= 0 && i < len(names) {
+		return names[i]
+	}
+	return ""
+}
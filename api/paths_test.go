@@ -0,0 +1,93 @@
+package onlinerf
+
+import (
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+// TestDecisionPathsConsistent проверяет, что пути решений согласованы
+// с порогами узлов и предсказаниями деревьев.
+func TestDecisionPathsConsistent(t *testing.T) {
+	cfg := PredictorConfig{
+		NumTrees:    2,
+		NumFeatures: 3,
+		TreeOptions: TreeOptions{
+			MaxDepth:            6,
+			MaxNodesPerTree:     100,
+			HoeffdingSplitDelta: 0.1,
+			MinSamplesPerLeaf:   10,
+		},
+	}
+	pred := mustPredictor(cfg)
+	trainSynthetic(pred, 2000, 3)
+
+	fv := features.FeatureVector{0.9, 0.2, 0.4}
+	paths := pred.DecisionPaths(fv)
+	if len(paths) != cfg.NumTrees {
+		t.Fatalf("expected %d paths, got %d", cfg.NumTrees, len(paths))
+	}
+
+	for _, path := range paths {
+		if len(path.Steps) == 0 {
+			t.Fatalf("expected tree %d to have split after training", path.Tree)
+		}
+		for _, s := range path.Steps {
+			left := s.Value <= s.Threshold
+			if left != (s.Direction == DirectionLeft) {
+				t.Fatalf("step %v has inconsistent direction", s)
+			}
+		}
+		if got := pred.trees[path.Tree].Predict(fv); got != path.Prediction {
+			t.Fatalf("path prediction %v differs from tree prediction %v", path.Prediction, got)
+		}
+		if path.Leaf.Pos+path.Leaf.Neg == 0 {
+			t.Fatalf("expected non-empty leaf statistics for %s", path)
+		}
+	}
+}
+
+// TestDecisionPathsUntrained проверяет пути для необученной модели.
+func TestDecisionPathsUntrained(t *testing.T) {
+	pred := mustPredictor(PredictorConfig{NumTrees: 2, NumFeatures: 1, TreeOptions: TreeOptions{MaxDepth: 3}})
+
+	for _, path := range pred.DecisionPaths(features.FeatureVector{0.5}) {
+		if len(path.Steps) != 0 {
+			t.Fatalf("expected no steps for untrained tree, got %d", len(path.Steps))
+		}
+		if path.Prediction != 0.5 {
+			t.Fatalf("expected prior prediction 0.5, got %v", path.Prediction)
+		}
+	}
+}
+
+// TestDecisionPathsFlipped проверяет, что для деревьев с инвертированным
+// кодом счётчики листа приводятся к исходным меткам.
+func TestDecisionPathsFlipped(t *testing.T) {
+	pred := mustPredictor(PredictorConfig{
+		NumTrees:    6,
+		NumFeatures: 2,
+		TreeOptions: TreeOptions{
+			MaxDepth:          2,
+			MinSamplesPerLeaf: 1 << 30,
+		},
+		Ensemble:   EnsembleLeveraging,
+		Leveraging: LeveragingConfig{OutputCodes: true},
+	})
+	for i := 0; i < 200; i++ {
+		pred.Update(features.FeatureVector{0.2, 0.7}, true)
+	}
+
+	flipped := 0
+	for _, path := range pred.DecisionPaths(features.FeatureVector{0.2, 0.7}) {
+		if pred.flipped(path.Tree) {
+			flipped++
+		}
+		if path.Prediction != 1 || path.Leaf.Neg != 0 || path.Leaf.Pos == 0 {
+			t.Fatalf("leaf counts disagree with the prediction: %s", path)
+		}
+	}
+	if flipped == 0 {
+		t.Fatalf("expected at least one tree with a flipped output code")
+	}
+}
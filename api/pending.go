@@ -0,0 +1,265 @@
+package onlinerf
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+// ErrUnknownID is returned by Feedback when no pending prediction with the
+// given id exists, either because it was never stored or because it expired
+// or was evicted.
+var ErrUnknownID = errors.New("onlinerf: unknown prediction id")
+
+// PendingConfig configures the buffer of predictions awaiting delayed
+// labels (see PredictWithID and Feedback).
+type PendingConfig struct {
+	// Capacity bounds the number of pending predictions. When the buffer is
+	// full the oldest prediction is evicted. Defaults to 10000.
+	Capacity int
+
+	// TTL is how long a prediction waits for its label before it expires.
+	// Defaults to 10 minutes.
+	TTL time.Duration
+
+	// Now returns the current time. Defaults to time.Now; tests and replays
+	// may supply their own clock.
+	Now func() time.Time
+}
+
+func (c PendingConfig) withDefaults() PendingConfig {
+	if c.Capacity <= 0 {
+		c.Capacity = 10000
+	}
+	if c.TTL <= 0 {
+		c.TTL = 10 * time.Minute
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+	return c
+}
+
+// PendingStats counts what happened to predictions stored with
+// PredictWithID.
+type PendingStats struct {
+	// Stored is the number of predictions added to the buffer.
+	Stored int64
+	// Matched is the number of Feedback calls that found their prediction.
+	Matched int64
+	// Unmatched is the number of Feedback calls with an unknown id.
+	Unmatched int64
+	// Expired is the number of predictions dropped after their TTL.
+	Expired int64
+	// Evicted is the number of predictions dropped because the buffer was
+	// full.
+	Evicted int64
+	// Pending is the number of predictions currently waiting for labels.
+	Pending int
+}
+
+type pendingEntry struct {
+	ID       string                 `json:"id"`
+	Features features.FeatureVector `json:"features"`
+	Created  time.Time              `json:"created"`
+}
+
+// pendingBuffer is a bounded store of pending predictions ordered by
+// creation time. Since all entries share one TTL, the front of the list is
+// always the next to expire. It has its own lock so that PredictWithID can
+// store entries while holding only the predictor's read lock.
+type pendingBuffer struct {
+	mu    sync.Mutex
+	cfg   PendingConfig
+	order *list.List
+	byID  map[string]*list.Element
+	stats PendingStats
+}
+
+func newPendingBuffer(cfg PendingConfig) *pendingBuffer {
+	return &pendingBuffer{
+		cfg:   cfg,
+		order: list.New(),
+		byID:  make(map[string]*list.Element),
+	}
+}
+
+// expired reports whether entry's TTL has passed at now.
+func (b *pendingBuffer) expired(entry *pendingEntry, now time.Time) bool {
+	return now.Sub(entry.Created) >= b.cfg.TTL
+}
+
+// expire drops entries older than the TTL. Callers must hold b.mu.
+func (b *pendingBuffer) expire(now time.Time) int {
+	n := 0
+	for e := b.order.Front(); e != nil; e = b.order.Front() {
+		if !b.expired(e.Value.(*pendingEntry), now) {
+			break
+		}
+		b.remove(e)
+		b.stats.Expired++
+		n++
+	}
+	return n
+}
+
+// put stores an entry in creation order, replacing any entry with the same
+// id. When the buffer is full the oldest entry is evicted, which may be the
+// new one itself. Callers must hold b.mu.
+func (b *pendingBuffer) put(entry *pendingEntry) {
+	if e, ok := b.byID[entry.ID]; ok {
+		b.remove(e)
+	}
+	for b.order.Len() >= b.cfg.Capacity {
+		front := b.order.Front()
+		b.stats.Evicted++
+		if entry.Created.Before(front.Value.(*pendingEntry).Created) {
+			return
+		}
+		b.remove(front)
+	}
+
+	// New predictions belong at the back; only restored ones walk further.
+	at := b.order.Back()
+	for at != nil && entry.Created.Before(at.Value.(*pendingEntry).Created) {
+		at = at.Prev()
+	}
+	if at == nil {
+		b.byID[entry.ID] = b.order.PushFront(entry)
+	} else {
+		b.byID[entry.ID] = b.order.InsertAfter(entry, at)
+	}
+	b.stats.Stored++
+}
+
+func (b *pendingBuffer) remove(e *list.Element) {
+	delete(b.byID, e.Value.(*pendingEntry).ID)
+	b.order.Remove(e)
+}
+
+func (b *pendingBuffer) add(id string, fv features.FeatureVector) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.cfg.Now()
+	b.expire(now)
+	b.put(&pendingEntry{
+		ID:       id,
+		Features: append(features.FeatureVector(nil), fv...),
+		Created:  now,
+	})
+}
+
+func (b *pendingBuffer) take(id string) (features.FeatureVector, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.cfg.Now()
+	b.expire(now)
+	e, ok := b.byID[id]
+	if ok && b.expired(e.Value.(*pendingEntry), now) {
+		b.remove(e)
+		b.stats.Expired++
+		ok = false
+	}
+	if !ok {
+		b.stats.Unmatched++
+		return nil, false
+	}
+	b.remove(e)
+	b.stats.Matched++
+	return e.Value.(*pendingEntry).Features, true
+}
+
+// PredictWithID predicts fv like Predict and keeps the feature vector in
+// the pending buffer under id, so that the model can be trained once the
+// label arrives via Feedback. Storing an id that is already pending
+// replaces the previous prediction.
+func (p *Predictor) PredictWithID(id string, fv features.FeatureVector) float64 {
+	p.pending.add(id, fv)
+	return p.Predict(fv)
+}
+
+// Feedback trains the model with the delayed label of the prediction stored
+// under id and removes it from the pending buffer. It returns ErrUnknownID
+// if the prediction is not pending. With FeatureDrift.MonitorPredict the
+// input is not fed to the feature monitor again, since PredictWithID already
+// did.
+func (p *Predictor) Feedback(id string, label bool) error {
+	fv, ok := p.pending.take(id)
+	if !ok {
+		return ErrUnknownID
+	}
+	p.update(fv, label, !p.cfg.FeatureDrift.MonitorPredict)
+	return nil
+}
+
+// PendingStats returns counters of the pending-prediction buffer.
+func (p *Predictor) PendingStats() PendingStats {
+	b := p.pending
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.expire(b.cfg.Now())
+	stats := b.stats
+	stats.Pending = b.order.Len()
+	return stats
+}
+
+// ExpirePending drops pending predictions whose TTL has passed and returns
+// how many were dropped. Expiry also happens lazily on every buffer access;
+// calling this periodically only bounds how long stale entries are kept.
+func (p *Predictor) ExpirePending() int {
+	b := p.pending
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.expire(b.cfg.Now())
+}
+
+// SavePending writes the pending predictions to w as JSON, oldest first.
+func (p *Predictor) SavePending(w io.Writer) error {
+	b := p.pending
+	b.mu.Lock()
+	entries := make([]*pendingEntry, 0, b.order.Len())
+	for e := b.order.Front(); e != nil; e = e.Next() {
+		entries = append(entries, e.Value.(*pendingEntry))
+	}
+	b.mu.Unlock()
+
+	return json.NewEncoder(w).Encode(entries)
+}
+
+// LoadPending reads pending predictions written by SavePending and adds
+// them to the buffer, keeping their original timestamps and creation order.
+// Entries that have already expired are counted as expired and dropped.
+func (p *Predictor) LoadPending(r io.Reader) error {
+	var entries []*pendingEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return err
+	}
+	for i, entry := range entries {
+		if entry == nil {
+			return fmt.Errorf("onlinerf: pending entry %d is null", i)
+		}
+	}
+
+	b := p.pending
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.cfg.Now()
+	for _, entry := range entries {
+		if b.expired(entry, now) {
+			b.stats.Expired++
+			continue
+		}
+		b.put(entry)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package onlinerf
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+// fakeClock — управляемые часы для проверки TTL.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func pendingPredictor(clock *fakeClock, capacity int) *Predictor {
+	return mustPredictor(PredictorConfig{
+		NumTrees:    1,
+		NumFeatures: 1,
+		TreeOptions: TreeOptions{
+			MaxDepth:          3,
+			MinSamplesPerLeaf: 1 << 30,
+		},
+		Pending: PendingConfig{Capacity: capacity, TTL: time.Minute, Now: clock.Now},
+	})
+}
+
+// TestFeedbackTrainsWithStoredFeatures проверяет, что Feedback обучает
+// модель на сохранённом векторе и удаляет его из буфера.
+func TestFeedbackTrainsWithStoredFeatures(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	pred := pendingPredictor(clock, 10)
+
+	if p := pred.PredictWithID("a", features.FeatureVector{0.3}); p != pred.Predict(features.FeatureVector{0.3}) {
+		t.Fatalf("PredictWithID must match Predict, got %v", p)
+	}
+	if err := pred.Feedback("a", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := pred.trees[0].Root.Stats.Pos; got != 1 {
+		t.Fatalf("expected one positive sample after feedback, got %v", got)
+	}
+	if err := pred.Feedback("a", true); !errors.Is(err, ErrUnknownID) {
+		t.Fatalf("expected ErrUnknownID for repeated feedback, got %v", err)
+	}
+
+	stats := pred.PendingStats()
+	if stats.Stored != 1 || stats.Matched != 1 || stats.Unmatched != 1 || stats.Pending != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+// TestFeedbackMonitorsInputOnce проверяет, что вход, переданный в
+// PredictWithID и затем в Feedback, попадает в монитор признаков один раз.
+func TestFeedbackMonitorsInputOnce(t *testing.T) {
+	for _, monitorPredict := range []bool{false, true} {
+		clock := &fakeClock{now: time.Unix(0, 0)}
+		pred := mustPredictor(PredictorConfig{
+			NumTrees:     1,
+			NumFeatures:  1,
+			TreeOptions:  TreeOptions{MaxDepth: 3},
+			FeatureDrift: FeatureDriftConfig{Method: FeatureDriftPSI, MonitorPredict: monitorPredict},
+			Pending:      PendingConfig{Capacity: 10, TTL: time.Minute, Now: clock.Now},
+		})
+
+		for i := 0; i < 5; i++ {
+			id := string(rune('a' + i))
+			pred.PredictWithID(id, features.FeatureVector{0.3})
+			if err := pred.Feedback(id, true); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if n := pred.FeatureDrift().Samples; n != 5 {
+			t.Fatalf("MonitorPredict=%v: expected 5 monitored samples, got %d", monitorPredict, n)
+		}
+	}
+}
+
+// TestPendingExpiryAndEviction проверяет истечение TTL и вытеснение
+// старейших предсказаний при переполнении буфера.
+func TestPendingExpiryAndEviction(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	pred := pendingPredictor(clock, 2)
+
+	pred.PredictWithID("a", features.FeatureVector{0.1})
+	clock.now = clock.now.Add(30 * time.Second)
+	pred.PredictWithID("b", features.FeatureVector{0.2})
+	pred.PredictWithID("c", features.FeatureVector{0.3}) // вытесняет "a"
+
+	if err := pred.Feedback("a", false); !errors.Is(err, ErrUnknownID) {
+		t.Fatalf("expected evicted prediction to be unknown, got %v", err)
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+	if n := pred.ExpirePending(); n != 2 {
+		t.Fatalf("expected 2 expired predictions, got %d", n)
+	}
+	if err := pred.Feedback("b", false); !errors.Is(err, ErrUnknownID) {
+		t.Fatalf("expected expired prediction to be unknown, got %v", err)
+	}
+
+	stats := pred.PendingStats()
+	want := PendingStats{Stored: 3, Unmatched: 2, Expired: 2, Evicted: 1}
+	if stats != want {
+		t.Fatalf("expected %+v, got %+v", want, stats)
+	}
+}
+
+// TestSaveLoadPending проверяет сохранение и восстановление буфера с
+// исходными отметками времени.
+func TestSaveLoadPending(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	src := pendingPredictor(clock, 10)
+	src.PredictWithID("old", features.FeatureVector{0.1})
+	clock.now = clock.now.Add(45 * time.Second)
+	src.PredictWithID("new", features.FeatureVector{0.9})
+
+	var buf bytes.Buffer
+	if err := src.SavePending(&buf); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	clock.now = clock.now.Add(30 * time.Second)
+	dst := pendingPredictor(clock, 10)
+	if err := dst.LoadPending(&buf); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if stats := dst.PendingStats(); stats.Pending != 1 || stats.Expired != 1 {
+		t.Fatalf("expected the old prediction to expire on load, got %+v", stats)
+	}
+	if err := dst.Feedback("new", true); err != nil {
+		t.Fatalf("expected restored prediction to match, got %v", err)
+	}
+}
+
+// TestLoadPendingKeepsCreationOrder проверяет, что восстановленное старое
+// предсказание встаёт в начало очереди, истекает по TTL раньше новых и не
+// принимает запоздавшую метку.
+func TestLoadPendingKeepsCreationOrder(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	src := pendingPredictor(clock, 10)
+	src.PredictWithID("old", features.FeatureVector{0.1})
+	var buf bytes.Buffer
+	if err := src.SavePending(&buf); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	clock.now = clock.now.Add(20 * time.Second)
+	dst := pendingPredictor(clock, 10)
+	dst.PredictWithID("new", features.FeatureVector{0.9})
+	if err := dst.LoadPending(&buf); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	clock.now = time.Unix(70, 0)
+	if err := dst.Feedback("old", true); !errors.Is(err, ErrUnknownID) {
+		t.Fatalf("expected the expired prediction to be rejected, got %v", err)
+	}
+	if got := dst.trees[0].Root; got != nil {
+		t.Fatalf("expected no training on an expired prediction")
+	}
+	stats := dst.PendingStats()
+	if stats.Matched != 0 || stats.Expired != 1 || stats.Pending != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if err := dst.Feedback("new", false); err != nil {
+		t.Fatalf("expected the newer prediction to match, got %v", err)
+	}
+}
+
+// TestLoadPendingRejectsNull проверяет ошибку для пустых записей.
+func TestLoadPendingRejectsNull(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	pred := pendingPredictor(clock, 10)
+	if err := pred.LoadPending(bytes.NewBufferString("[null]")); err == nil {
+		t.Fatalf("expected an error for a null entry")
+	}
+	if stats := pred.PendingStats(); stats.Stored != 0 {
+		t.Fatalf("expected nothing to be stored, got %+v", stats)
+	}
+}
+
+// TestLoadPendingIntoFullBuffer проверяет, что при переполнении
+// вытесняется самое старое предсказание, даже если оно восстановлено.
+func TestLoadPendingIntoFullBuffer(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	src := pendingPredictor(clock, 10)
+	src.PredictWithID("old", features.FeatureVector{0.1})
+	var buf bytes.Buffer
+	_ = src.SavePending(&buf)
+
+	clock.now = clock.now.Add(time.Second)
+	dst := pendingPredictor(clock, 1)
+	dst.PredictWithID("new", features.FeatureVector{0.9})
+	if err := dst.LoadPending(&buf); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if stats := dst.PendingStats(); stats.Evicted != 1 || stats.Pending != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if err := dst.Feedback("new", true); err != nil {
+		t.Fatalf("expected the newer prediction to be kept, got %v", err)
+	}
+}
@@ -1,10 +1,13 @@
 package onlinerf
 
 import (
+	"errors"
 	"sync"
 
 	"github.com/kudmo/onlinerf/internal/aggregator"
+	"github.com/kudmo/onlinerf/internal/calibration"
 	"github.com/kudmo/onlinerf/internal/forest"
+	"github.com/kudmo/onlinerf/internal/monitor"
 	"github.com/kudmo/onlinerf/api/features"
 )
 
@@ -16,7 +19,8 @@ import (
 type Predictor struct {
 	cfg PredictorConfig
 
-	trees []*forest.Tree
+	trees   []*forest.Tree
+	treeCfg forest.TreeConfig
 
 	embedder   features.Embedder
 	normalizer features.Normalizer
@@ -25,6 +29,35 @@ type Predictor struct {
 	numFeatures int
 	initialized bool
 
+	// Running class priors and prequential metrics used by the imbalance
+	// handling (see ImbalanceConfig).
+	classPos float64
+	classNeg float64
+	metrics  ClassificationMetrics
+
+	// Optional calibration layer (see CalibrationConfig).
+	calibrator     calibration.Calibrator
+	calReliability *calibration.Reliability
+	rawReliability *calibration.Reliability
+
+	// Forest-level drift handling (see ReplacementConfig).
+	monitors     []*treeMonitor
+	ensembleErrs errorWindow
+	updates      int64
+
+	// featureMonitor tracks input drift; it has its own lock so Predict can
+	// feed it while holding only a read lock.
+	featureMonitor *monitor.Monitor
+
+	// pending holds predictions awaiting delayed labels.
+	pending *pendingBuffer
+
+	// members holds per-tree Leveraging Bagging state; nil in other modes.
+	members []*leveragingMember
+	// patches holds per-tree Streaming Random Patches state; nil in other
+	// modes.
+	patches []*patchMember
+
 	mu sync.RWMutex
 }
 
@@ -34,25 +67,45 @@ type Predictor struct {
 // If cfg.EmbedderFactory is nil, IdentityEmbedder is used and callers are
 // responsible for constructing FeatureVector values directly.
 //
+// If cfg.NumFeatures is zero it is inferred from the embedder or the schema.
+// NewPredictor returns an error if the embedder reports a dimension of zero,
+// since a forest without features could never split.
+//
 // Example:
 //
 //	cfg := onlinerf.PredictorConfig{
-//		NumTrees:        20,
-//		NumFeatures:     16,
-//		MaxDepth:        10,
-//		MaxNodesPerTree: 500,
+//		NumTrees:    20,
+//		NumFeatures: 16,
+//		TreeOptions: onlinerf.TreeOptions{
+//			MaxDepth:        10,
+//			MaxNodesPerTree: 500,
+//		},
+//	}
+//	model, err := onlinerf.NewPredictor(cfg)
+//	if err != nil {
+//		log.Fatal(err)
 //	}
-//	model := onlinerf.NewPredictor(cfg)
 //	prob := model.Predict(features.FeatureVector{0.1, 0.5 /* ... */})
 //	_ = prob
-func NewPredictor(cfg PredictorConfig) *Predictor {
+func NewPredictor(cfg PredictorConfig) (*Predictor, error) {
+	cfg.Replacement = cfg.Replacement.withDefaults()
+	cfg.FeatureDrift = cfg.FeatureDrift.withDefaults()
+	cfg.Pending = cfg.Pending.withDefaults()
+	cfg.Leveraging = cfg.Leveraging.withDefaults()
+	cfg.RandomPatches = cfg.RandomPatches.withDefaults()
 	p := &Predictor{
-		cfg: cfg,
+		cfg:     cfg,
+		pending: newPendingBuffer(cfg.Pending),
 	}
 
 	// Feature pipeline
 	if cfg.EmbedderFactory != nil {
-		p.embedder = cfg.EmbedderFactory.NewEmbedder(cfg.FeatureConfig)
+		fcfg := cfg.FeatureConfig
+		if cfg.Schema != nil && fcfg.NumNumeric == 0 && fcfg.NumCategorical == 0 {
+			fcfg.NumNumeric = len(cfg.Schema.NumericNames())
+			fcfg.NumCategorical = len(cfg.Schema.CategoricalNames())
+		}
+		p.embedder = cfg.EmbedderFactory.NewEmbedder(fcfg)
 	} else {
 		p.embedder = features.IdentityEmbedder{}
 	}
@@ -65,26 +118,80 @@ func NewPredictor(cfg PredictorConfig) *Predictor {
 
 	p.agg = aggregator.MeanAggregator{}
 
-	p.initForest(cfg.NumFeatures)
-	return p
-}
+	if p.calibrator = newCalibrator(cfg.Calibration); p.calibrator != nil {
+		p.calReliability = newReliability(cfg.Calibration)
+		p.rawReliability = newReliability(cfg.Calibration)
+	}
 
-func (p *Predictor) initForest(numFeatures int) {
+	numFeatures := cfg.NumFeatures
+	if numFeatures == 0 {
+		if d, ok := p.embedder.(features.DimensionedEmbedder); ok {
+			numFeatures = d.Dim()
+			if numFeatures == 0 {
+				return nil, errors.New("onlinerf: embedder has no features; set Schema, FeatureConfig or NumFeatures")
+			}
+		} else if cfg.Schema != nil && cfg.EmbedderFactory == nil {
+			numFeatures = cfg.Schema.Len()
+		}
+	}
+	p.initForest(numFeatures)
+	return p, nil
+}
 
-	treeCfg := forest.TreeConfig{
-		MaxDepth:            p.cfg.MaxDepth,
-		MaxNodes:            p.cfg.MaxNodesPerTree,
-		HoeffdingSplitDelta: p.cfg.HoeffdingSplitDelta,
-		MinSamplesPerLeaf:   p.cfg.MinSamplesPerLeaf,
+// newTreeConfig maps opts to the configuration of a single tree. Every
+// ensemble of Hoeffding trees builds its trees with it.
+func newTreeConfig(opts TreeOptions) forest.TreeConfig {
+	return forest.TreeConfig{
+		MaxDepth:            opts.MaxDepth,
+		MaxNodes:            opts.MaxNodesPerTree,
+		HoeffdingSplitDelta: opts.HoeffdingSplitDelta,
+		MinSamplesPerLeaf:   opts.MinSamplesPerLeaf,
+		UseDriftDetection:   opts.UseDriftDetection,
+		DriftAlpha:          opts.DriftAlpha,
+		LeafPrediction:      opts.LeafPrediction,
+		LeafLearningRate:    opts.LeafLearningRate,
+		SplitCriterion:      opts.SplitCriterion,
+		TieThreshold:        opts.TieThreshold,
+		GracePeriod:         opts.GracePeriod,
+		PrePrune:            opts.PrePrune,
+		MinSplitGain:        opts.MinSplitGain,
+		FadingFactor:        opts.FadingFactor,
+		SplitMode:           opts.SplitMode,
+		ReevaluationPeriod:  opts.ReevaluationPeriod,
+		ObliqueSplits:       opts.ObliqueSplits,
+		ObliqueFeatures:     opts.ObliqueFeatures,
+		ObliqueLearningRate: opts.ObliqueLearningRate,
 	}
+}
+
+func (p *Predictor) initForest(numFeatures int) {
+	treeCfg := newTreeConfig(p.cfg.TreeOptions)
 
+	p.numFeatures = numFeatures
 	p.trees = make([]*forest.Tree, p.cfg.NumTrees)
+	p.monitors = make([]*treeMonitor, p.cfg.NumTrees)
+	p.ensembleErrs = newErrorWindow(p.cfg.Replacement.Window)
 
 	for i := 0; i < p.cfg.NumTrees; i++ {
-		p.trees[i] = forest.NewTree(treeCfg, numFeatures)
+		treeCfg.Seed = forest.DeriveSeed(p.cfg.Seed, i)
+		p.trees[i] = p.newMemberTree(treeCfg)
+		p.monitors[i] = newTreeMonitor(p.cfg.Replacement)
+	}
+	if p.cfg.Ensemble == EnsembleRandomPatches {
+		p.patches = make([]*patchMember, p.cfg.NumTrees)
+		for i := range p.patches {
+			p.patches[i] = p.newPatchMember()
+		}
+	}
+	if p.cfg.Ensemble == EnsembleLeveraging {
+		p.members = make([]*leveragingMember, p.cfg.NumTrees)
+		for i, t := range p.trees {
+			p.members[i] = p.newLeveragingMember(t)
+		}
 	}
+	p.treeCfg = treeCfg
+	p.featureMonitor = newFeatureMonitor(p.cfg.FeatureDrift, numFeatures)
 
-	p.numFeatures = numFeatures
 	p.initialized = true
 }
 
@@ -97,24 +204,39 @@ func (p *Predictor) initForest(numFeatures int) {
 // Example:
 //
 //	fv := features.FeatureVector{0.3, 0.8 /* ... */ }
-//	p := onlinerf.NewPredictor(cfg)
+//	p, _ := onlinerf.NewPredictor(cfg)
 //	score := p.Predict(fv)
 func (p *Predictor) Predict(fv features.FeatureVector) float64 {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	// Apply feature pipeline.
+	return p.predictLocked(p.scoringInput(fv))
+}
+
+// scoringInput applies the feature pipeline to an input that is about to be
+// scored and, with FeatureDrift.MonitorPredict, records it in the feature
+// monitor. Every predict method goes through it so that drift statistics do
+// not depend on which one the caller uses. Callers must hold p.mu.
+func (p *Predictor) scoringInput(fv features.FeatureVector) features.FeatureVector {
 	embedded := fv
 	if p.normalizer != nil {
 		embedded = p.normalizer.Transform(embedded)
 	}
+	if p.featureMonitor != nil && p.cfg.FeatureDrift.MonitorPredict {
+		p.featureMonitor.Add(embedded)
+	}
+	return embedded
+}
 
+// predictLocked aggregates the tree predictions for an already transformed
+// feature vector. Callers must hold p.mu.
+func (p *Predictor) predictLocked(embedded features.FeatureVector) float64 {
 	probs := make([]float64, 0, len(p.trees))
-	for _, t := range p.trees {
+	for i, t := range p.trees {
 		if t == nil {
 			continue
 		}
-		probs = append(probs, t.Predict(embedded))
+		probs = append(probs, p.treePredict(i, embedded))
 	}
 
 	return p.agg.Aggregate(probs)
@@ -125,7 +247,8 @@ func (p *Predictor) Predict(fv features.FeatureVector) float64 {
 // The feature vector fv must have the same dimensionality as in Predict.
 // label should be true for the positive class and false otherwise.
 // Internally the sample is passed through the optional normalizer (if enabled)
-// and then applied to every tree in the forest.
+// and then applied to every tree in the forest, weighted according to the
+// configured ImbalanceStrategy.
 //
 // Example:
 //
@@ -134,8 +257,14 @@ func (p *Predictor) Predict(fv features.FeatureVector) float64 {
 //		model.Update(fv, s.Y)  // online update
 //	}
 func (p *Predictor) Update(fv features.FeatureVector, label bool) {
+	p.update(fv, label, true)
+}
+
+// update trains the model with a labeled sample. observe tells whether the
+// input is fed to the feature monitor; it is false for inputs the monitor
+// has already seen when they were predicted.
+func (p *Predictor) update(fv features.FeatureVector, label bool, observe bool) {
 	p.mu.Lock()
-	defer p.mu.Unlock()
 
 	if p.normalizer != nil {
 		p.normalizer.Update(fv)
@@ -145,10 +274,57 @@ func (p *Predictor) Update(fv features.FeatureVector, label bool) {
 		embedded = p.normalizer.Transform(embedded)
 	}
 
-	for _, t := range p.trees {
-		if t == nil {
-			continue
+	if p.featureMonitor != nil && observe {
+		p.featureMonitor.Add(embedded)
+	}
+
+	// Prequential bookkeeping: score the sample before learning from it.
+	p.updates++
+	replacing := p.cfg.Replacement.Policy != ReplaceNever
+	if track := p.trackMetrics(); track || p.calibrator != nil || replacing {
+		var score float64
+		if replacing {
+			score = p.monitorTrees(embedded, label)
+		} else {
+			score = p.predictLocked(embedded)
+		}
+		if track {
+			p.metrics.add(score >= p.decisionThreshold(), label)
+		}
+		if p.calibrator != nil {
+			p.updateCalibration(score, label)
+		}
+	}
+	p.observeClass(label)
+
+	switch p.cfg.Ensemble {
+	case EnsembleLeveraging:
+		p.updateLeveraging(embedded, label)
+	case EnsembleRandomPatches:
+		p.updatePatches(embedded, label)
+	default:
+		for _, t := range p.trees {
+			if t == nil {
+				continue
+			}
+			t.UpdateWeighted(embedded, label, p.sampleWeight(t, label))
+		}
+	}
+
+	var replaced []TreeReplacement
+	if replacing {
+		replaced = p.replaceTrees()
+	}
+
+	metrics := p.metrics
+	p.mu.Unlock()
+
+	if hook := p.cfg.Imbalance.MetricsHook; hook != nil {
+		hook(metrics)
+	}
+	if hook := p.cfg.Replacement.OnReplace; hook != nil {
+		for _, r := range replaced {
+			hook(r)
 		}
-		t.Update(embedded, label)
 	}
 }
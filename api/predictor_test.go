@@ -6,22 +6,36 @@ import (
 	"github.com/kudmo/onlinerf/api/features"
 )
 
+// mustPredictor создаёт Predictor и паникует, если конфигурация
+// некорректна.
+func mustPredictor(cfg PredictorConfig) *Predictor {
+	pred, err := NewPredictor(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return pred
+}
+
 // TestNewPredictorInitialization проверяет, что Predictor корректно
 // инициализируется и создаёт ожидаемое количество деревьев.
 func TestNewPredictorInitialization(t *testing.T) {
 	cfg := PredictorConfig{
-		NumTrees:            5,
-		NumFeatures:         2,
-		MaxDepth:            10,
-		MaxNodesPerTree:     100,
-		HoeffdingSplitDelta: 1e-3,
-		MinSamplesPerLeaf:   2,
-		UseDriftDetection:   true,
-		DriftAlpha:          0.05,
+		NumTrees:    5,
+		NumFeatures: 2,
+		TreeOptions: TreeOptions{
+			MaxDepth:            10,
+			MaxNodesPerTree:     100,
+			HoeffdingSplitDelta: 1e-3,
+			MinSamplesPerLeaf:   2,
+			UseDriftDetection:   true,
+			DriftAlpha:          0.05,
+		},
 	}
 
-	pred := NewPredictor(cfg)
-
+	pred, err := NewPredictor(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
 	if pred == nil {
 		t.Fatalf("expected non-nil predictor")
 	}
@@ -42,13 +56,15 @@ func TestNewPredictorInitialization(t *testing.T) {
 // вероятность в диапазоне [0,1] и стабильно вызывается несколько раз.
 func TestPredictInitialRange(t *testing.T) {
 	cfg := PredictorConfig{
-		NumTrees:          3,
-		NumFeatures:       3,
-		MaxDepth:          5,
-		MaxNodesPerTree:   50,
-		MinSamplesPerLeaf: 1,
+		NumTrees:    3,
+		NumFeatures: 3,
+		TreeOptions: TreeOptions{
+			MaxDepth:          5,
+			MaxNodesPerTree:   50,
+			MinSamplesPerLeaf: 1,
+		},
 	}
-	pred := NewPredictor(cfg)
+	pred := mustPredictor(cfg)
 
 	fv := features.FeatureVector{0.1, 0.5, 1.0}
 
@@ -71,13 +87,15 @@ func TestPredictInitialRange(t *testing.T) {
 // предсказания могут измениться (онлайн-обучение влияет на модель).
 func TestUpdateChangesPrediction(t *testing.T) {
 	cfg := PredictorConfig{
-		NumTrees:          1,
-		NumFeatures:       1,
-		MaxDepth:          3,
-		MaxNodesPerTree:   10,
-		MinSamplesPerLeaf: 1,
+		NumTrees:    1,
+		NumFeatures: 1,
+		TreeOptions: TreeOptions{
+			MaxDepth:          3,
+			MaxNodesPerTree:   10,
+			MinSamplesPerLeaf: 1,
+		},
 	}
-	pred := NewPredictor(cfg)
+	pred := mustPredictor(cfg)
 
 	fv := features.FeatureVector{0.3}
 
@@ -104,13 +122,15 @@ func TestUpdateChangesPrediction(t *testing.T) {
 // для последовательности примеров и не приводит к панике.
 func TestUpdateSequentialSamples(t *testing.T) {
 	cfg := PredictorConfig{
-		NumTrees:          2,
-		NumFeatures:       1,
-		MaxDepth:          4,
-		MaxNodesPerTree:   20,
-		MinSamplesPerLeaf: 1,
+		NumTrees:    2,
+		NumFeatures: 1,
+		TreeOptions: TreeOptions{
+			MaxDepth:          4,
+			MaxNodesPerTree:   20,
+			MinSamplesPerLeaf: 1,
+		},
 	}
-	pred := NewPredictor(cfg)
+	pred := mustPredictor(cfg)
 
 	samples := []struct {
 		fv    features.FeatureVector
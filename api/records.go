@@ -0,0 +1,112 @@
+package onlinerf
+
+import (
+	"errors"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+// ErrNoSchema is returned by the record-based methods when the predictor was
+// created without PredictorConfig.Schema.
+var ErrNoSchema = errors.New("onlinerf: predictor has no schema")
+
+// EmbedRecord maps a named record to the FeatureVector consumed by the
+// forest, using PredictorConfig.Schema.
+//
+// If an EmbedderFactory is configured, the record is converted with
+// Schema.Raw and passed to the embedder; otherwise Schema.Embed is used and
+// the vector has one value per schema column.
+func (p *Predictor) EmbedRecord(record map[string]any) (features.FeatureVector, error) {
+	schema := p.cfg.Schema
+	if schema == nil {
+		return nil, ErrNoSchema
+	}
+	if p.cfg.EmbedderFactory == nil {
+		return schema.Embed(record)
+	}
+	raw, err := schema.Raw(record)
+	if err != nil {
+		return nil, err
+	}
+	return p.embedder.Embed(raw), nil
+}
+
+// PredictRecord is like Predict but takes a named record described by
+// PredictorConfig.Schema.
+//
+// Example:
+//
+//	score, err := model.PredictRecord(map[string]any{
+//		"cpu": 0.7, "mem": 0.4, "env": "prod",
+//	})
+func (p *Predictor) PredictRecord(record map[string]any) (float64, error) {
+	fv, err := p.EmbedRecord(record)
+	if err != nil {
+		return 0, err
+	}
+	return p.Predict(fv), nil
+}
+
+// UpdateRecord is like Update but takes a named record described by
+// PredictorConfig.Schema. Records rejected by the schema leave the model
+// unchanged.
+func (p *Predictor) UpdateRecord(record map[string]any, label bool) error {
+	schema := p.cfg.Schema
+	if schema == nil {
+		return ErrNoSchema
+	}
+	if p.cfg.EmbedderFactory == nil {
+		fv, err := schema.Embed(record)
+		if err != nil {
+			return err
+		}
+		p.Update(fv, label)
+		return nil
+	}
+
+	raw, err := schema.Raw(record)
+	if err != nil {
+		return err
+	}
+	p.UpdateRaw(raw, label)
+	return nil
+}
+
+// PredictRaw embeds raw with the configured embedder and returns the
+// probability of the positive class.
+func (p *Predictor) PredictRaw(raw features.RawFeatureVector) float64 {
+	return p.Predict(p.embedder.Embed(raw))
+}
+
+// UpdateRaw embeds raw with the configured embedder and trains on it.
+//
+// If the embedder implements features.LabelObserver (e.g. TargetEncoder),
+// it observes the label only after the sample has been embedded and applied
+// to the forest, so the sample's own label never leaks into its features.
+func (p *Predictor) UpdateRaw(raw features.RawFeatureVector, label bool) {
+	p.Update(p.embedder.Embed(raw), label)
+	if obs, ok := p.embedder.(features.LabelObserver); ok {
+		obs.Observe(raw, label)
+	}
+}
+
+// FeatureNames returns the names of the embedded features, or nil if they
+// are unknown (no schema, or an embedder that changes the layout without
+// implementing features.FeatureNamer).
+func (p *Predictor) FeatureNames() []string {
+	schema := p.cfg.Schema
+	if schema == nil {
+		return nil
+	}
+
+	var names []string
+	if p.cfg.EmbedderFactory == nil {
+		names = schema.Names()
+	} else if namer, ok := p.embedder.(features.FeatureNamer); ok {
+		names = namer.FeatureNames(schema.NumericNames(), schema.CategoricalNames())
+	}
+	if len(names) != p.numFeatures {
+		return nil
+	}
+	return names
+}
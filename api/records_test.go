@@ -0,0 +1,152 @@
+package onlinerf
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+// TestRecordsWithSchema проверяет обучение и предсказание по именованным
+// записям и появление имён признаков в путях решений и объяснениях.
+func TestRecordsWithSchema(t *testing.T) {
+	schema, err := features.NewSchema([]features.Column{
+		features.NumericColumn("cpu"),
+		features.NumericColumn("mem"),
+		features.CategoricalColumn("env"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected schema error: %v", err)
+	}
+
+	pred := mustPredictor(PredictorConfig{
+		NumTrees: 2,
+		TreeOptions: TreeOptions{
+			MaxDepth:            6,
+			HoeffdingSplitDelta: 0.1,
+			MinSamplesPerLeaf:   10,
+		},
+		Schema: schema,
+	})
+	if pred.numFeatures != schema.Len() {
+		t.Fatalf("expected NumFeatures to default to %d, got %d", schema.Len(), pred.numFeatures)
+	}
+
+	rng := rand.New(rand.NewSource(6))
+	for i := 0; i < 1000; i++ {
+		cpu := rng.Float64()
+		rec := map[string]any{"cpu": cpu, "mem": rng.Float64(), "env": "prod"}
+		if err := pred.UpdateRecord(rec, cpu > 0.5); err != nil {
+			t.Fatalf("unexpected update error: %v", err)
+		}
+	}
+
+	rec := map[string]any{"cpu": 0.9, "mem": 0.1, "env": "prod"}
+	if _, err := pred.PredictRecord(rec); err != nil {
+		t.Fatalf("unexpected predict error: %v", err)
+	}
+	if _, err := pred.PredictRecord(map[string]any{"cpu": 0.9}); !errors.Is(err, features.ErrMissingColumn) {
+		t.Fatalf("expected ErrMissingColumn, got %v", err)
+	}
+
+	fv, _ := pred.EmbedRecord(rec)
+	if names := pred.Explain(fv).FeatureNames; len(names) != 3 || names[0] != "cpu" {
+		t.Fatalf("unexpected explanation names: %v", names)
+	}
+	for _, path := range pred.DecisionPaths(fv) {
+		if len(path.Steps) == 0 || path.Steps[0].Name != "cpu" {
+			t.Fatalf("expected named root split on cpu, got %s", path)
+		}
+	}
+}
+
+// TestRecordsWithoutSchema проверяет ошибку при отсутствии схемы.
+func TestRecordsWithoutSchema(t *testing.T) {
+	pred := mustPredictor(PredictorConfig{NumTrees: 1, NumFeatures: 1, TreeOptions: TreeOptions{MaxDepth: 2}})
+	if err := pred.UpdateRecord(map[string]any{"x": 1}, true); !errors.Is(err, ErrNoSchema) {
+		t.Fatalf("expected ErrNoSchema, got %v", err)
+	}
+}
+
+// TestRecordsWithOneHotEmbedder проверяет, что размерность и имена
+// признаков выводятся из встроенного эмбеддера и схемы.
+func TestRecordsWithOneHotEmbedder(t *testing.T) {
+	schema, _ := features.NewSchema([]features.Column{
+		features.NumericColumn("cpu"),
+		features.CategoricalColumn("env"),
+	})
+	pred := mustPredictor(PredictorConfig{
+		NumTrees: 1,
+		TreeOptions: TreeOptions{
+			MaxDepth:          4,
+			MinSamplesPerLeaf: 5,
+		},
+		Schema:          schema,
+		FeatureConfig:   features.FeatureConfig{MaxCategories: 3},
+		EmbedderFactory: features.OneHotEmbedderFactory{},
+	})
+	if pred.numFeatures != 5 {
+		t.Fatalf("expected 5 embedded features, got %d", pred.numFeatures)
+	}
+
+	if err := pred.UpdateRecord(map[string]any{"cpu": 0.3, "env": "prod"}, true); err != nil {
+		t.Fatalf("unexpected update error: %v", err)
+	}
+	names := pred.FeatureNames()
+	if len(names) != 5 || names[0] != "cpu" || names[1] != "env=prod" || names[4] != "env=<other>" {
+		t.Fatalf("unexpected feature names: %v", names)
+	}
+}
+
+// TestOneHotEmbedderWithoutLayout проверяет, что лес без признаков не
+// создаётся молча, если эмбеддер не знает размерность входа.
+func TestOneHotEmbedderWithoutLayout(t *testing.T) {
+	pred, err := NewPredictor(PredictorConfig{
+		NumTrees: 1,
+		TreeOptions: TreeOptions{
+			MaxDepth: 4,
+		},
+		EmbedderFactory: features.OneHotEmbedderFactory{},
+	})
+	if err == nil || pred != nil {
+		t.Fatalf("expected NewPredictor to fail without a feature layout")
+	}
+}
+
+// TestUpdateRecordTargetEncoding проверяет, что целевое кодирование делает
+// категориальный признак информативным для леса.
+func TestUpdateRecordTargetEncoding(t *testing.T) {
+	schema, _ := features.NewSchema([]features.Column{
+		features.CategoricalColumn("user"),
+	})
+	pred := mustPredictor(PredictorConfig{
+		NumTrees: 3,
+		TreeOptions: TreeOptions{
+			MaxDepth:            5,
+			HoeffdingSplitDelta: 0.1,
+			MinSamplesPerLeaf:   20,
+		},
+		Schema:          schema,
+		EmbedderFactory: features.TargetEncoderFactory{},
+	})
+
+	rng := rand.New(rand.NewSource(7))
+	users := []string{"a", "b", "c", "d", "e", "f"}
+	bad := map[string]bool{"b": true, "e": true}
+	for i := 0; i < 3000; i++ {
+		u := users[rng.Intn(len(users))]
+		if err := pred.UpdateRecord(map[string]any{"user": u}, bad[u]); err != nil {
+			t.Fatalf("unexpected update error: %v", err)
+		}
+	}
+
+	pBad, _ := pred.PredictRecord(map[string]any{"user": "b"})
+	pGood, _ := pred.PredictRecord(map[string]any{"user": "a"})
+	if pBad <= 0.5 || pGood >= 0.5 {
+		t.Fatalf("expected target encoding to separate users, bad=%v good=%v", pBad, pGood)
+	}
+	if names := pred.FeatureNames(); len(names) != 1 || names[0] != "user:target" {
+		t.Fatalf("unexpected feature names: %v", names)
+	}
+}
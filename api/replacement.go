@@ -0,0 +1,261 @@
+package onlinerf
+
+import (
+	"math"
+	"sort"
+
+	"github.com/kudmo/onlinerf/api/features"
+	"github.com/kudmo/onlinerf/internal/forest"
+)
+
+// ReplacementPolicy selects when a Predictor replaces whole trees whose
+// recent error is significantly worse than the ensemble's.
+type ReplacementPolicy int
+
+const (
+	// ReplaceNever keeps all trees for the lifetime of the predictor.
+	ReplaceNever ReplacementPolicy = iota
+	// ReplaceWorstK periodically replaces up to K trees whose windowed
+	// error is significantly above the ensemble's.
+	ReplaceWorstK
+	// ReplaceOnDrift monitors, per tree, how often the tree errs where the
+	// ensemble is right; when a drift detector flags an increase and the
+	// tree's windowed error exceeds the ensemble's, the tree is replaced.
+	ReplaceOnDrift
+)
+
+// String returns a short name of the policy for logging.
+func (r ReplacementPolicy) String() string {
+	switch r {
+	case ReplaceWorstK:
+		return "worst-k"
+	case ReplaceOnDrift:
+		return "drift"
+	default:
+		return "never"
+	}
+}
+
+// ReplacementConfig configures forest-level drift handling.
+type ReplacementConfig struct {
+	// Policy selects when trees are replaced. Defaults to ReplaceNever.
+	Policy ReplacementPolicy
+
+	// Window is the number of recent samples over which tree and ensemble
+	// error rates are measured. Defaults to 500.
+	Window int
+
+	// Period is the number of updates between ReplaceWorstK checks.
+	// Defaults to 1000.
+	Period int
+
+	// K is the maximum number of trees replaced per ReplaceWorstK check.
+	// Defaults to 1.
+	K int
+
+	// Delta is the confidence parameter of the Hoeffding test used by
+	// ReplaceWorstK to decide whether a tree's error is significantly
+	// above the ensemble's. Defaults to 0.01.
+	Delta float64
+
+	// DriftAlpha is the significance level of the per-tree drift detectors
+	// used by ReplaceOnDrift. Defaults to 0.002.
+	DriftAlpha float64
+
+	// OnReplace, if set, is called for every replaced tree. It is called
+	// after Update has released the predictor lock.
+	OnReplace func(TreeReplacement)
+}
+
+// TreeReplacement describes a tree that was replaced by a fresh one.
+type TreeReplacement struct {
+	// Tree is the index of the replaced tree.
+	Tree int
+	// Policy is the policy that triggered the replacement.
+	Policy ReplacementPolicy
+	// TreeError and EnsembleError are the windowed error rates at the
+	// time of replacement.
+	TreeError     float64
+	EnsembleError float64
+	// Updates is the number of Update calls seen by the predictor.
+	Updates int64
+}
+
+// errorWindow is a fixed-size ring buffer of prediction outcomes.
+type errorWindow struct {
+	buf    []bool
+	next   int
+	errors int
+}
+
+func newErrorWindow(size int) errorWindow {
+	return errorWindow{buf: make([]bool, 0, size)}
+}
+
+func (w *errorWindow) add(err bool) {
+	if len(w.buf) < cap(w.buf) {
+		w.buf = append(w.buf, err)
+	} else {
+		if w.buf[w.next] {
+			w.errors--
+		}
+		w.buf[w.next] = err
+		w.next = (w.next + 1) % len(w.buf)
+	}
+	if err {
+		w.errors++
+	}
+}
+
+func (w *errorWindow) rate() float64 {
+	if len(w.buf) == 0 {
+		return 0
+	}
+	return float64(w.errors) / float64(len(w.buf))
+}
+
+// treeMonitor tracks the recent performance of a single tree.
+type treeMonitor struct {
+	errs    errorWindow
+	excess  *forest.DriftDetector
+	drifted bool
+}
+
+func (c ReplacementConfig) withDefaults() ReplacementConfig {
+	if c.Window <= 0 {
+		c.Window = 500
+	}
+	if c.Period <= 0 {
+		c.Period = 1000
+	}
+	if c.K <= 0 {
+		c.K = 1
+	}
+	if c.Delta <= 0 {
+		c.Delta = 0.01
+	}
+	if c.DriftAlpha <= 0 {
+		c.DriftAlpha = 0.002
+	}
+	return c
+}
+
+func newTreeMonitor(cfg ReplacementConfig) *treeMonitor {
+	m := &treeMonitor{errs: newErrorWindow(cfg.Window)}
+	if cfg.Policy == ReplaceOnDrift {
+		m.excess = forest.NewBoundedADWIN(cfg.DriftAlpha, cfg.Window)
+	}
+	return m
+}
+
+// TreeErrors returns the windowed error rate of every tree and of the whole
+// ensemble. Errors are only tracked when a replacement policy is enabled.
+func (p *Predictor) TreeErrors() (trees []float64, ensemble float64) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	trees = make([]float64, len(p.monitors))
+	for i, m := range p.monitors {
+		trees[i] = m.errs.rate()
+	}
+	return trees, p.ensembleErrs.rate()
+}
+
+// monitorTrees scores the sample with every tree before training, records
+// tree and ensemble errors and returns the ensemble score. Callers must
+// hold p.mu.
+func (p *Predictor) monitorTrees(embedded features.FeatureVector, label bool) float64 {
+	preds := make([]float64, len(p.trees))
+	probs := make([]float64, 0, len(p.trees))
+	for i, t := range p.trees {
+		if t == nil {
+			continue
+		}
+		preds[i] = p.treePredict(i, embedded)
+		probs = append(probs, preds[i])
+	}
+	score := p.agg.Aggregate(probs)
+
+	ensembleErr := (score >= 0.5) != label
+	p.ensembleErrs.add(ensembleErr)
+	for i, t := range p.trees {
+		if t == nil {
+			continue
+		}
+		m := p.monitors[i]
+		treeErr := (preds[i] >= 0.5) != label
+		m.errs.add(treeErr)
+		if m.excess != nil && m.excess.Add(treeErr && !ensembleErr) {
+			m.drifted = true
+		}
+	}
+	return score
+}
+
+// replaceTrees applies the replacement policy after a training step and
+// returns the replacements it made. Callers must hold p.mu.
+func (p *Predictor) replaceTrees() []TreeReplacement {
+	cfg := p.cfg.Replacement
+	ensemble := p.ensembleErrs.rate()
+
+	var replace []int
+	switch cfg.Policy {
+	case ReplaceOnDrift:
+		for i, m := range p.monitors {
+			if m.drifted {
+				m.drifted = false
+				if m.errs.rate() > ensemble {
+					replace = append(replace, i)
+				}
+			}
+		}
+	case ReplaceWorstK:
+		if p.updates%int64(cfg.Period) != 0 {
+			return nil
+		}
+		n := float64(len(p.ensembleErrs.buf))
+		eps := math.Sqrt(math.Log(1/cfg.Delta) / (2 * n))
+		for i, m := range p.monitors {
+			if m.errs.rate()-ensemble > eps {
+				replace = append(replace, i)
+			}
+		}
+		sort.SliceStable(replace, func(a, b int) bool {
+			return p.monitors[replace[a]].errs.rate() > p.monitors[replace[b]].errs.rate()
+		})
+		if len(replace) > cfg.K {
+			replace = replace[:cfg.K]
+		}
+	}
+
+	events := make([]TreeReplacement, 0, len(replace))
+	for _, i := range replace {
+		events = append(events, TreeReplacement{
+			Tree:          i,
+			Policy:        cfg.Policy,
+			TreeError:     p.monitors[i].errs.rate(),
+			EnsembleError: ensemble,
+			Updates:       p.updates,
+		})
+		p.resetTree(i)
+	}
+	return events
+}
+
+// resetTree replaces tree i with a fresh tree. The new tree's seed is drawn
+// from the old tree's random stream to keep runs reproducible. Callers must
+// hold p.mu.
+func (p *Predictor) resetTree(i int) {
+	cfg := p.treeCfg
+	if old := p.trees[i]; old != nil {
+		cfg.Seed = old.Rand.Uint64()
+	}
+	p.trees[i] = p.newMemberTree(cfg)
+	p.monitors[i] = newTreeMonitor(p.cfg.Replacement)
+	if p.members != nil {
+		p.members[i] = p.newLeveragingMember(p.trees[i])
+	}
+	if p.patches != nil {
+		p.patches[i] = p.newPatchMember()
+	}
+}
@@ -0,0 +1,78 @@
+package onlinerf
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+	"github.com/kudmo/onlinerf/internal/forest"
+)
+
+// corruptTree заменяет дерево i деревом, обученным на инвертированных
+// метках, так что оно почти всегда ошибается.
+func corruptTree(pred *Predictor, i int) {
+	tree := forest.NewTree(pred.treeCfg, pred.numFeatures)
+	rng := rand.New(rand.NewSource(39))
+	for j := 0; j < 2000; j++ {
+		fv := features.FeatureVector{rng.Float64(), rng.Float64(), rng.Float64()}
+		tree.Update(fv, !(fv[0] > 0.5))
+	}
+	pred.trees[i] = tree
+}
+
+// TestReplacementPolicies проверяет, что испорченное дерево заменяется
+// свежим при обеих политиках и что о замене сообщает колбэк.
+func TestReplacementPolicies(t *testing.T) {
+	for _, policy := range []ReplacementPolicy{ReplaceWorstK, ReplaceOnDrift} {
+		var events []TreeReplacement
+		pred := mustPredictor(PredictorConfig{
+			NumTrees:    4,
+			NumFeatures: 3,
+			TreeOptions: TreeOptions{
+				MaxDepth:            4,
+				MinSamplesPerLeaf:   20,
+				HoeffdingSplitDelta: 0.01,
+				GracePeriod:         50,
+			},
+			Seed: 7,
+			Replacement: ReplacementConfig{
+				Policy:    policy,
+				Window:    200,
+				Period:    300,
+				OnReplace: func(r TreeReplacement) { events = append(events, r) },
+			},
+		})
+		trainSynthetic(pred, 1000, 40)
+		corruptTree(pred, 2)
+		trainSynthetic(pred, 1000, 41)
+
+		if len(events) == 0 {
+			t.Fatalf("%s: expected the corrupted tree to be replaced", policy)
+		}
+		for _, e := range events {
+			if e.Tree != 2 {
+				t.Fatalf("%s: unexpected replacement of tree %d (error %v, ensemble %v)", policy, e.Tree, e.TreeError, e.EnsembleError)
+			}
+			if e.TreeError <= e.EnsembleError || e.Policy != policy {
+				t.Fatalf("%s: unexpected replacement event %+v", policy, e)
+			}
+		}
+
+		trees, ensemble := pred.TreeErrors()
+		if len(trees) != 4 || trees[2] > ensemble+0.2 {
+			t.Fatalf("%s: expected the replacement tree to recover, errors=%v ensemble=%v", policy, trees, ensemble)
+		}
+	}
+}
+
+// TestReplaceNeverKeepsTrees проверяет, что по умолчанию деревья не
+// заменяются.
+func TestReplaceNeverKeepsTrees(t *testing.T) {
+	pred := mustPredictor(PredictorConfig{NumTrees: 3, NumFeatures: 3, TreeOptions: TreeOptions{MaxDepth: 4, MinSamplesPerLeaf: 20, HoeffdingSplitDelta: 0.01}})
+	corruptTree(pred, 1)
+	bad := pred.trees[1]
+	trainSynthetic(pred, 2000, 42)
+	if pred.trees[1] != bad {
+		t.Fatalf("expected trees to be kept with ReplaceNever")
+	}
+}
@@ -0,0 +1,128 @@
+package onlinerf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/kudmo/onlinerf/internal/forest"
+)
+
+// RNGState is a snapshot of every random stream owned by a Predictor.
+type RNGState struct {
+	// Trees holds the state of every tree's stream, indexed like the trees
+	// of the forest.
+	Trees []uint64 `json:"trees"`
+
+	// Background holds the streams of the background trees trained in
+	// EnsembleRandomPatches mode, keyed by the index of the tree they back.
+	Background map[int]uint64 `json:"background,omitempty"`
+}
+
+// RNGState returns the state of every random stream of the predictor,
+// including the background trees of EnsembleRandomPatches. Together with the
+// model it allows resuming training exactly where it left off; the same
+// values are stored as "rng_state" and "background_rng_state" in ExportJSON
+// documents and can be restored with LoadRNGState.
+func (p *Predictor) RNGState() RNGState {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	state := RNGState{Trees: make([]uint64, len(p.trees))}
+	for i, t := range p.trees {
+		if t != nil {
+			state.Trees[i] = t.Rand.State()
+		}
+		if b := p.background(i); b != nil {
+			if state.Background == nil {
+				state.Background = make(map[int]uint64)
+			}
+			state.Background[i] = b.Rand.State()
+		}
+	}
+	return state
+}
+
+// SetRNGState restores random stream states previously returned by RNGState.
+// The predictor must have the same trees and background trees as the one
+// the state was taken from.
+func (p *Predictor) SetRNGState(state RNGState) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.setRNGState(state)
+}
+
+// LoadRNGState restores the random streams stored in a document written by
+// ExportJSON. The document must contain every tree of the forest.
+func (p *Predictor) LoadRNGState(r io.Reader) error {
+	var doc forestDocument
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if doc.NumTrees != len(p.trees) || len(doc.Trees) != len(p.trees) {
+		return fmt.Errorf("onlinerf: document has %d of %d trees, want all %d",
+			len(doc.Trees), doc.NumTrees, len(p.trees))
+	}
+	state := RNGState{Trees: make([]uint64, len(p.trees))}
+	seen := make([]bool, len(p.trees))
+	for _, d := range doc.Trees {
+		if d.Index < 0 || d.Index >= len(p.trees) || seen[d.Index] {
+			return fmt.Errorf("onlinerf: invalid tree index %d in document", d.Index)
+		}
+		seen[d.Index] = true
+		state.Trees[d.Index] = d.RNGState
+		if d.BackgroundRNGState != nil {
+			if state.Background == nil {
+				state.Background = make(map[int]uint64)
+			}
+			state.Background[d.Index] = *d.BackgroundRNGState
+		}
+	}
+	return p.setRNGState(state)
+}
+
+// setRNGState validates state against the predictor and restores it.
+// Callers must hold p.mu.
+func (p *Predictor) setRNGState(state RNGState) error {
+	if len(state.Trees) != len(p.trees) {
+		return fmt.Errorf("onlinerf: got %d rng states for %d trees", len(state.Trees), len(p.trees))
+	}
+	backgrounds := 0
+	for i := range p.trees {
+		if p.background(i) == nil {
+			continue
+		}
+		backgrounds++
+		if _, ok := state.Background[i]; !ok {
+			return fmt.Errorf("onlinerf: missing rng state for background tree %d", i)
+		}
+	}
+	if len(state.Background) != backgrounds {
+		return fmt.Errorf("onlinerf: got %d background rng states for %d background trees",
+			len(state.Background), backgrounds)
+	}
+
+	for i, t := range p.trees {
+		if t != nil {
+			t.Rand.SetState(state.Trees[i])
+		}
+		if b := p.background(i); b != nil {
+			b.Rand.SetState(state.Background[i])
+		}
+	}
+	return nil
+}
+
+// background returns the background tree of tree i, or nil if there is none.
+// Callers must hold p.mu.
+func (p *Predictor) background(i int) *forest.Tree {
+	if p.patches == nil || p.patches[i] == nil {
+		return nil
+	}
+	return p.patches[i].background
+}
@@ -0,0 +1,181 @@
+package onlinerf
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/kudmo/onlinerf/api/features"
+	"github.com/kudmo/onlinerf/internal/forest"
+)
+
+func seededConfig(seed int64) PredictorConfig {
+	return PredictorConfig{
+		NumTrees:    4,
+		NumFeatures: 3,
+		TreeOptions: TreeOptions{
+			MaxDepth:            6,
+			HoeffdingSplitDelta: 0.1,
+			MinSamplesPerLeaf:   10,
+			LeafPrediction:      LeafNBAdaptive,
+			TieThreshold:        0.05,
+		},
+		Seed: seed,
+	}
+}
+
+// TestSeedReproducible проверяет, что одинаковый seed и порядок примеров
+// дают побайтно одинаковые деревья и предсказания.
+func TestSeedReproducible(t *testing.T) {
+	a := mustPredictor(seededConfig(42))
+	b := mustPredictor(seededConfig(42))
+	trainSynthetic(a, 2000, 16)
+	trainSynthetic(b, 2000, 16)
+
+	var ja, jb bytes.Buffer
+	if err := a.ExportJSON(&ja, ExportOptions{}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if err := b.ExportJSON(&jb, ExportOptions{}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if !bytes.Equal(ja.Bytes(), jb.Bytes()) {
+		t.Fatalf("models trained with the same seed differ")
+	}
+
+	fv := []float64{0.7, 0.1, 0.3}
+	if a.Predict(fv) != b.Predict(fv) {
+		t.Fatalf("predictions differ: %v vs %v", a.Predict(fv), b.Predict(fv))
+	}
+}
+
+// TestSeedIndependentStreams проверяет, что деревья получают разные
+// потоки случайных чисел, а разные seed — разные состояния.
+func TestSeedIndependentStreams(t *testing.T) {
+	a := mustPredictor(seededConfig(1)).RNGState()
+	b := mustPredictor(seededConfig(2)).RNGState()
+
+	seen := make(map[uint64]bool)
+	for _, s := range a.Trees {
+		if seen[s] {
+			t.Fatalf("trees share a random stream: %v", a)
+		}
+		seen[s] = true
+	}
+	for i := range a.Trees {
+		if a.Trees[i] == b.Trees[i] {
+			t.Fatalf("different seeds produced the same stream for tree %d", i)
+		}
+	}
+}
+
+// TestRNGStateRoundTrip проверяет сохранение и восстановление состояния ГСЧ.
+func TestRNGStateRoundTrip(t *testing.T) {
+	a := mustPredictor(seededConfig(3))
+	state := a.RNGState()
+	a.trees[0].Rand.Uint64()
+
+	if err := a.SetRNGState(state); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := a.RNGState()
+	for i := range state.Trees {
+		if got.Trees[i] != state.Trees[i] {
+			t.Fatalf("state of tree %d not restored", i)
+		}
+	}
+	if err := a.SetRNGState(RNGState{Trees: state.Trees[:1]}); err == nil {
+		t.Fatalf("expected error for wrong number of states")
+	}
+}
+
+// TestRNGStateRandomPatches проверяет, что снимок включает потоки фоновых
+// деревьев и что после восстановления — напрямую или из ExportJSON —
+// обучение повторяется побитово.
+func TestRNGStateRandomPatches(t *testing.T) {
+	a := newPatchesPredictor()
+	b := newPatchesPredictor()
+	rng := rand.New(rand.NewSource(50))
+	train := func(n int, concept func(features.FeatureVector) bool) {
+		for i := 0; i < n; i++ {
+			fv := wideSample(rng)
+			a.Update(fv, concept(fv))
+			b.Update(fv, concept(fv))
+		}
+	}
+	first := func(fv features.FeatureVector) bool { return fv[0]+fv[1] > 1 }
+	second := func(fv features.FeatureVector) bool { return fv[0]+fv[1] <= 1 }
+
+	train(3000, first)
+	for i := 0; i < 20 && len(a.RNGState().Background) == 0; i++ {
+		train(10, second)
+	}
+	state := a.RNGState()
+	if len(state.Background) == 0 {
+		t.Fatalf("expected background trees after the concept switch")
+	}
+	if err := b.SetRNGState(RNGState{Trees: state.Trees}); err == nil {
+		t.Fatalf("expected error for missing background states")
+	}
+	backgrounds := map[*forest.Tree]bool{}
+	for i := range a.trees {
+		if bg := a.background(i); bg != nil {
+			backgrounds[bg] = true
+		}
+	}
+
+	// scramble draws from every stream of b so that only a restored state
+	// can make it follow a again.
+	scramble := func() {
+		for i, tree := range b.trees {
+			tree.Rand.Uint64()
+			if bg := b.background(i); bg != nil {
+				bg.Rand.Uint64()
+			}
+		}
+	}
+	scramble()
+	if err := b.SetRNGState(state); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	train(3000, second)
+
+	swapped := false
+	for _, tree := range a.trees {
+		swapped = swapped || backgrounds[tree]
+	}
+	if !swapped {
+		t.Fatalf("expected a background tree to replace its tree")
+	}
+	assertSameModel(t, a, b)
+
+	var doc bytes.Buffer
+	if err := a.ExportJSON(&doc, ExportOptions{}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	scramble()
+	if err := b.LoadRNGState(&doc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	train(1000, first)
+	assertSameModel(t, a, b)
+}
+
+// assertSameModel проверяет, что модели совпадают побайтно.
+func assertSameModel(t *testing.T, a, b *Predictor) {
+	t.Helper()
+	var ja, jb bytes.Buffer
+	if err := a.ExportJSON(&ja, ExportOptions{}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if err := b.ExportJSON(&jb, ExportOptions{}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if !bytes.Equal(ja.Bytes(), jb.Bytes()) {
+		t.Fatalf("restored model diverged")
+	}
+	fv := wideSample(rand.New(rand.NewSource(51)))
+	if a.Predict(fv) != b.Predict(fv) {
+		t.Fatalf("predictions differ: %v vs %v", a.Predict(fv), b.Predict(fv))
+	}
+}
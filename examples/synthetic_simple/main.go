@@ -25,16 +25,21 @@ func main() {
 	const numFeatures = 7
 
 	cfg := onlinerf.PredictorConfig{
-		NumTrees:            10,
-		NumFeatures:         numFeatures,
-		MaxDepth:            20,
-		MaxNodesPerTree:     300,
-		HoeffdingSplitDelta: 0.1,
-		MinSamplesPerLeaf:   5,
-		UseDriftDetection:   false,
+		NumTrees:    10,
+		NumFeatures: numFeatures,
+		TreeOptions: onlinerf.TreeOptions{
+			MaxDepth:            20,
+			MaxNodesPerTree:     300,
+			HoeffdingSplitDelta: 0.1,
+			MinSamplesPerLeaf:   5,
+			UseDriftDetection:   false,
+		},
 	}
 
-	model := onlinerf.NewPredictor(cfg)
+	model, err := onlinerf.NewPredictor(cfg)
+	if err != nil {
+		panic(err)
+	}
 
 	type sample struct {
 		cpu   float64
@@ -0,0 +1,208 @@
+// Package calibration provides online probability calibrators that map raw
+// ensemble scores to calibrated probabilities.
+package calibration
+
+import (
+	"math"
+	"sort"
+)
+
+// Calibrator maps raw scores in [0,1] to calibrated probabilities and learns
+// the mapping online from (score, label) pairs.
+//
+// Calibrate must be safe to call concurrently with other Calibrate calls;
+// Update requires exclusive access.
+type Calibrator interface {
+	Calibrate(score float64) float64
+	Update(score float64, label bool)
+}
+
+// scoreEps keeps logits finite for scores of exactly 0 or 1.
+const scoreEps = 1e-6
+
+func logit(p float64) float64 {
+	p = math.Min(math.Max(p, scoreEps), 1-scoreEps)
+	return math.Log(p / (1 - p))
+}
+
+func sigmoid(z float64) float64 {
+	return 1 / (1 + math.Exp(-z))
+}
+
+// Platt performs online Platt scaling: p = sigmoid(A*logit(score) + B),
+// fitted by stochastic gradient descent on the log loss. It starts from the
+// identity mapping (A=1, B=0).
+type Platt struct {
+	A float64
+	B float64
+
+	learningRate float64
+	n            float64
+}
+
+// NewPlatt creates a Platt scaler with the given base learning rate.
+func NewPlatt(learningRate float64) *Platt {
+	return &Platt{A: 1, learningRate: learningRate}
+}
+
+func (c *Platt) Calibrate(score float64) float64 {
+	return sigmoid(c.A*logit(score) + c.B)
+}
+
+func (c *Platt) Update(score float64, label bool) {
+	y := 0.0
+	if label {
+		y = 1.0
+	}
+	x := logit(score)
+	grad := sigmoid(c.A*x+c.B) - y
+
+	// Decay the step size so the parameters settle on stationary streams.
+	c.n++
+	lr := c.learningRate / math.Sqrt(1+c.n/1000)
+	c.A -= lr * grad * x
+	c.B -= lr * grad
+}
+
+type point struct {
+	score float64
+	label float64
+}
+
+// block is a run of the fitted isotonic step function.
+type block struct {
+	upper float64
+	value float64
+}
+
+// Isotonic fits an isotonic (monotone non-decreasing) regression with the
+// pool-adjacent-violators algorithm over a sliding window of the most recent
+// (score, label) pairs. The fit is refreshed every refitEvery updates; until
+// the first fit it returns scores unchanged.
+type Isotonic struct {
+	window     []point
+	next       int
+	full       bool
+	refitEvery int
+	pending    int
+
+	blocks []block
+}
+
+// NewIsotonic creates an isotonic calibrator over the last window pairs,
+// refitted every refitEvery updates.
+func NewIsotonic(window, refitEvery int) *Isotonic {
+	if window < 1 {
+		window = 1
+	}
+	if refitEvery < 1 {
+		refitEvery = 1
+	}
+	return &Isotonic{
+		window:     make([]point, 0, window),
+		refitEvery: refitEvery,
+	}
+}
+
+func (c *Isotonic) Calibrate(score float64) float64 {
+	if len(c.blocks) == 0 {
+		return score
+	}
+	i := sort.Search(len(c.blocks), func(i int) bool { return c.blocks[i].upper >= score })
+	if i == len(c.blocks) {
+		i--
+	}
+	return c.blocks[i].value
+}
+
+func (c *Isotonic) Update(score float64, label bool) {
+	p := point{score: score}
+	if label {
+		p.label = 1
+	}
+
+	if len(c.window) < cap(c.window) {
+		c.window = append(c.window, p)
+	} else {
+		c.window[c.next] = p
+		c.next = (c.next + 1) % len(c.window)
+	}
+
+	c.pending++
+	if c.pending >= c.refitEvery {
+		c.pending = 0
+		c.refit()
+	}
+}
+
+// refit runs pool-adjacent-violators over the current window.
+func (c *Isotonic) refit() {
+	pts := append([]point(nil), c.window...)
+	sort.Slice(pts, func(i, j int) bool { return pts[i].score < pts[j].score })
+
+	type pool struct {
+		upper  float64
+		sum    float64
+		weight float64
+	}
+	pools := make([]pool, 0, len(pts))
+	for _, p := range pts {
+		pools = append(pools, pool{upper: p.score, sum: p.label, weight: 1})
+		for len(pools) > 1 {
+			last := pools[len(pools)-1]
+			prev := pools[len(pools)-2]
+			if prev.sum/prev.weight <= last.sum/last.weight {
+				break
+			}
+			pools = pools[:len(pools)-1]
+			pools[len(pools)-1] = pool{
+				upper:  last.upper,
+				sum:    prev.sum + last.sum,
+				weight: prev.weight + last.weight,
+			}
+		}
+	}
+
+	c.blocks = c.blocks[:0]
+	for _, p := range pools {
+		c.blocks = append(c.blocks, block{upper: p.upper, value: p.sum / p.weight})
+	}
+}
+
+// Bin is one bucket of a reliability diagram.
+type Bin struct {
+	Count    float64
+	SumProb  float64
+	SumLabel float64
+}
+
+// Reliability accumulates predicted probabilities and outcomes into equally
+// wide bins for reliability diagrams.
+type Reliability struct {
+	Bins []Bin
+}
+
+// NewReliability creates a reliability accumulator with the given number of
+// bins over [0,1].
+func NewReliability(bins int) *Reliability {
+	if bins < 1 {
+		bins = 1
+	}
+	return &Reliability{Bins: make([]Bin, bins)}
+}
+
+// Add records a predicted probability and the observed label.
+func (r *Reliability) Add(prob float64, label bool) {
+	i := int(prob * float64(len(r.Bins)))
+	if i >= len(r.Bins) {
+		i = len(r.Bins) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	r.Bins[i].Count++
+	r.Bins[i].SumProb += prob
+	if label {
+		r.Bins[i].SumLabel++
+	}
+}
@@ -0,0 +1,111 @@
+package forest
+
+import "math"
+
+// ClassCounts holds the (possibly weighted) number of samples per class.
+type ClassCounts struct {
+	Pos float64
+	Neg float64
+}
+
+// Total returns the number of samples of both classes.
+func (c ClassCounts) Total() float64 {
+	return c.Pos + c.Neg
+}
+
+// SplitCriterion scores candidate binary splits of a leaf. Trees compare the
+// merits of the two best candidates with the Hoeffding bound, which needs
+// the range of the merit function.
+type SplitCriterion interface {
+	// Merit returns the quality of splitting a node with class counts
+	// parent into branches with class counts left and right. Higher is
+	// better; not splitting has merit 0.
+	Merit(parent, left, right ClassCounts) float64
+
+	// Range returns the range R of Merit for a node with the given class
+	// counts, used in the Hoeffding bound.
+	Range(parent ClassCounts) float64
+}
+
+// GiniCriterion measures the decrease in Gini impurity. It is the default.
+type GiniCriterion struct{}
+
+func (GiniCriterion) Merit(parent, left, right ClassCounts) float64 {
+	total := left.Total() + right.Total()
+	if total == 0 {
+		return 0
+	}
+	weighted := left.Total()/total*giniImpurity(left) + right.Total()/total*giniImpurity(right)
+	return giniImpurity(parent) - weighted
+}
+
+// Range returns 1, the conventional bound for Gini gain.
+func (GiniCriterion) Range(ClassCounts) float64 {
+	return 1
+}
+
+func giniImpurity(c ClassCounts) float64 {
+	total := c.Total()
+	if total == 0 {
+		return 0
+	}
+	p := c.Pos / total
+	return 1 - p*p - (1-p)*(1-p)
+}
+
+// InfoGainCriterion measures the decrease in entropy (in bits).
+type InfoGainCriterion struct{}
+
+func (InfoGainCriterion) Merit(parent, left, right ClassCounts) float64 {
+	total := left.Total() + right.Total()
+	if total == 0 {
+		return 0
+	}
+	weighted := left.Total()/total*entropy(left) + right.Total()/total*entropy(right)
+	return entropy(parent) - weighted
+}
+
+// Range returns log2 of the number of classes.
+func (InfoGainCriterion) Range(ClassCounts) float64 {
+	return math.Log2(2)
+}
+
+func entropy(c ClassCounts) float64 {
+	total := c.Total()
+	if total == 0 {
+		return 0
+	}
+	h := 0.0
+	for _, n := range [2]float64{c.Pos, c.Neg} {
+		if n > 0 {
+			p := n / total
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
+
+// HellingerCriterion measures the Hellinger distance between the
+// distributions of the two classes over the branches. It does not depend on
+// class priors, which makes it robust for heavily skewed streams.
+type HellingerCriterion struct{}
+
+func (HellingerCriterion) Merit(parent, left, right ClassCounts) float64 {
+	pos := left.Pos + right.Pos
+	neg := left.Neg + right.Neg
+	if pos == 0 || neg == 0 {
+		return 0
+	}
+
+	d := 0.0
+	for _, b := range [2]ClassCounts{left, right} {
+		diff := math.Sqrt(b.Pos/pos) - math.Sqrt(b.Neg/neg)
+		d += diff * diff
+	}
+	return math.Sqrt(d)
+}
+
+// Range returns sqrt(2), the maximum Hellinger distance.
+func (HellingerCriterion) Range(ClassCounts) float64 {
+	return math.Sqrt2
+}
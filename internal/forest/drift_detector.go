@@ -11,6 +11,10 @@ type DriftDetector struct {
 	sumSquares float64
 	alpha      float64
 	minWindow  int
+	// maxWindow bounds the number of retained observations; 0 means
+	// unbounded. Bounded detectors also use the per-cut ADWIN bound (see
+	// detect).
+	maxWindow int
 }
 
 // NewADWIN creates a new drift detector with the given significance level alpha.
@@ -26,6 +30,19 @@ func NewADWIN(alpha float64) *DriftDetector {
 	}
 }
 
+// NewBoundedADWIN creates a drift detector that keeps at most maxWindow of
+// the most recent observations, bounding both memory and the cost of Add.
+// It is suited for long-lived streams such as per-tree error rates.
+//
+// Unlike NewADWIN it tests each cut with the ADWIN bound for the difference
+// of two sub-window means, corrected for the number of cuts, so a long-lived
+// detector does not fire on noise just because it is tested many times.
+func NewBoundedADWIN(alpha float64, maxWindow int) *DriftDetector {
+	d := NewADWIN(alpha)
+	d.maxWindow = maxWindow
+	return d
+}
+
 // Add feeds a new binary observation into the detector.
 // It returns true if drift is detected and the internal window was reset.
 func (d *DriftDetector) Add(value bool) bool {
@@ -42,6 +59,15 @@ func (d *DriftDetector) Add(value bool) bool {
 	d.sum += x
 	d.sumSquares += x * x
 
+	// Forget the oldest observation once the window is full.
+	if d.maxWindow > 0 && d.width > d.maxWindow {
+		old := d.window[0]
+		d.window = d.window[1:]
+		d.width--
+		d.sum -= old
+		d.sumSquares -= old * old
+	}
+
 	// If there is not enough data yet, never signal drift.
 	if d.width < d.minWindow {
 		return false
@@ -63,13 +89,19 @@ func (d *DriftDetector) detect() bool {
 	// Compute epsilon using a Hoeffding-style bound for the sample mean.
 	eps := math.Sqrt(2 * variance * math.Log(2.0/d.alpha) / n)
 
+	// Bounded detectors correct the confidence for the number of cut
+	// points tested (ADWIN's delta' = delta / n).
+	logTerm := math.Log(2.0 * n / d.alpha)
+
 	// Split the window at different cut points and test for a significant
-	// difference between the left and right means.
+	// difference between the left and right means. The left sum is carried
+	// over between cut points so a full scan is linear in the window size.
+	leftSum := 0.0
+	for j := 0; j < d.minWindow-1; j++ {
+		leftSum += d.window[j]
+	}
 	for i := d.minWindow; i <= d.width-d.minWindow; i++ {
-		leftSum := 0.0
-		for j := 0; j < i; j++ {
-			leftSum += d.window[j]
-		}
+		leftSum += d.window[i-1]
 		rightSum := d.sum - leftSum
 
 		nLeft := float64(i)
@@ -78,6 +110,13 @@ func (d *DriftDetector) detect() bool {
 		meanLeft := leftSum / nLeft
 		meanRight := rightSum / nRight
 
+		if d.maxWindow > 0 {
+			// ADWIN bound for the difference of two sub-window means,
+			// based on the harmonic mean of their sizes.
+			m := 1.0 / (1.0/nLeft + 1.0/nRight)
+			eps = math.Sqrt(2*variance*logTerm/m) + 2.0/(3.0*m)*logTerm
+		}
+
 		if math.Abs(meanLeft-meanRight) > eps {
 			// Drift detected — reset the window and report true.
 			d.reset()
@@ -88,6 +127,15 @@ func (d *DriftDetector) detect() bool {
 	return false
 }
 
+// Estimate returns the mean of the current window, e.g. the recent error
+// rate when the detector is fed errors, or 0 if the window is empty.
+func (d *DriftDetector) Estimate() float64 {
+	if d.width == 0 {
+		return 0
+	}
+	return d.sum / float64(d.width)
+}
+
 func (d *DriftDetector) reset() {
 	d.window = d.window[:0]
 	d.width = 0
@@ -0,0 +1,90 @@
+package forest
+
+import "github.com/kudmo/onlinerf/api/features"
+
+// SplitMode selects how a tree decides on and revisits splits.
+type SplitMode int
+
+const (
+	// SplitVFDT is the classic Hoeffding tree: a leaf splits once its best
+	// candidate beats the runner-up by ε, and splits are permanent.
+	SplitVFDT SplitMode = iota
+	// SplitEFDT is the Extremely Fast Decision Tree (Hoeffding Anytime
+	// Tree): a leaf splits as soon as its best candidate beats not
+	// splitting by ε, internal nodes keep their statistics, and a split is
+	// replaced (regrowing its subtree) or pruned when another choice
+	// becomes significantly better.
+	SplitEFDT
+)
+
+func (c TreeConfig) reevaluationPeriod() float64 {
+	if c.ReevaluationPeriod > 0 {
+		return float64(c.ReevaluationPeriod)
+	}
+	return float64(c.GracePeriod)
+}
+
+// updateInternal maintains the statistics and leaf model of an internal
+// node in EFDT mode and periodically re-evaluates its split.
+func (n *Node) updateInternal(fv features.FeatureVector, label bool, weight float64, cfg TreeConfig) {
+	n.Stats.Update(label, weight)
+	for i, v := range fv {
+		if fs, ok := n.FeatureStats[i]; ok {
+			fs.Update(v, label, weight)
+		}
+	}
+	if n.SplitFeature == ObliqueFeature && n.ObliqueStat != nil {
+		n.ObliqueStat.Update(n.Oblique.Margin(fv), label, weight)
+	}
+	if n.Model != nil {
+		n.Model.Update(fv, label, weight, cfg.leafLearningRate())
+	}
+
+	if n.Stats.Total()-n.LastSplitAttempt < cfg.reevaluationPeriod() {
+		return
+	}
+	n.LastSplitAttempt = n.Stats.Total()
+	n.reevaluate(cfg)
+}
+
+// reevaluate compares the node's current split with the best candidate and
+// with not splitting at all. If either is better by the Hoeffding bound,
+// the split is replaced by a fresh one or the node becomes a leaf again.
+func (n *Node) reevaluate(cfg TreeConfig) {
+	parent := ClassCounts{Pos: n.Stats.Pos, Neg: n.Stats.Neg}
+	criterion := cfg.criterion()
+
+	// The null split (turning back into a leaf) has merit 0.
+	bestFeature, bestGain, prune := 0, 0.0, true
+	current := 0.0
+	if n.SplitFeature == ObliqueFeature && n.ObliqueStat != nil {
+		left, right := n.ObliqueStat.Counts()
+		current = criterion.Merit(parent, left, right)
+	}
+	for _, i := range sortedFeatures(n.FeatureStats) {
+		left, right := n.FeatureStats[i].Counts()
+		gain := criterion.Merit(parent, left, right)
+		if i == n.SplitFeature {
+			current = gain
+		}
+		if gain > bestGain {
+			bestFeature, bestGain, prune = i, gain, false
+		}
+	}
+	if !prune && bestFeature == n.SplitFeature {
+		return
+	}
+
+	epsilon := hoeffdingBound(criterion.Range(parent), cfg.HoeffdingSplitDelta, n.Stats.Total())
+	if bestGain-current <= epsilon && epsilon >= cfg.TieThreshold {
+		return
+	}
+
+	if prune {
+		n.IsLeaf = true
+		n.Left = nil
+		n.Right = nil
+		return
+	}
+	n.splitOn(bestFeature, cfg)
+}
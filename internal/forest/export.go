@@ -0,0 +1,263 @@
+package forest
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+// DriftStatus describes the state of a leaf's drift detector.
+type DriftStatus struct {
+	Width int     `json:"width"`
+	Mean  float64 `json:"mean"`
+}
+
+// NodeExport is a serializable view of a tree node. Internal nodes carry the
+// split; label counts are aggregated from the leaves below the node.
+type NodeExport struct {
+	ID          int          `json:"id"`
+	Depth       int          `json:"depth"`
+	Leaf        bool         `json:"leaf"`
+	Feature     *int         `json:"feature,omitempty"`
+	FeatureName string       `json:"feature_name,omitempty"`
+	Threshold   *float64     `json:"threshold,omitempty"`
+	Oblique     *LinearModel `json:"oblique,omitempty"`
+	Model       *LinearModel `json:"model,omitempty"`
+	Pos         float64      `json:"pos"`
+	Neg         float64      `json:"neg"`
+	Prediction  *float64     `json:"prediction,omitempty"`
+	Drift       *DriftStatus `json:"drift,omitempty"`
+	Left        *NodeExport  `json:"left,omitempty"`
+	Right       *NodeExport  `json:"right,omitempty"`
+}
+
+// TreeExport is a serializable view of a whole tree.
+type TreeExport struct {
+	NumFeatures int         `json:"num_features"`
+	NumNodes    int         `json:"num_nodes"`
+	NumLeaves   int         `json:"num_leaves"`
+	Depth       int         `json:"depth"`
+	RNGState    uint64      `json:"rng_state,string"`
+	Root        *NodeExport `json:"root,omitempty"`
+}
+
+// Export builds a serializable view of the tree. names optionally maps
+// feature indices to human-readable names.
+//
+// Leaves report the probability they predict for the mean of the samples
+// they have seen, so naive Bayes and logistic leaves are rendered by their
+// model rather than by raw label counts.
+func (t *Tree) Export(names []string) TreeExport {
+	e := TreeExport{NumFeatures: t.NumFeatures, RNGState: t.Rand.State()}
+	if t.Root == nil {
+		return e
+	}
+
+	id := 0
+	e.Root = t.exportNode(t.Root, names, &id, &e)
+	return e
+}
+
+// Invert rewrites the export of a tree trained on negated labels in terms
+// of the original labels: class counts are swapped, probabilities are
+// replaced by their complement and leaf models are negated.
+func (e *TreeExport) Invert() {
+	invertNode(e.Root)
+}
+
+func invertNode(n *NodeExport) {
+	if n == nil {
+		return
+	}
+	n.Pos, n.Neg = n.Neg, n.Pos
+	if n.Prediction != nil {
+		p := 1 - *n.Prediction
+		n.Prediction = &p
+	}
+	if n.Drift != nil {
+		n.Drift.Mean = 1 - n.Drift.Mean
+	}
+	if n.Model != nil {
+		// The model is shared with the live tree, so negate a copy.
+		m := n.Model.clone()
+		for j := range m.Weights {
+			m.Weights[j] = -m.Weights[j]
+		}
+		m.Bias = -m.Bias
+		n.Model = m
+	}
+	invertNode(n.Left)
+	invertNode(n.Right)
+}
+
+func (t *Tree) exportNode(n *Node, names []string, id *int, e *TreeExport) *NodeExport {
+	out := &NodeExport{
+		ID:    *id,
+		Depth: n.Depth,
+		Leaf:  n.IsLeaf,
+	}
+	*id++
+
+	e.NumNodes++
+	if n.Depth > e.Depth {
+		e.Depth = n.Depth
+	}
+
+	if n.IsLeaf {
+		e.NumLeaves++
+		out.Pos = n.Stats.Pos
+		out.Neg = n.Stats.Neg
+		out.Model = n.Model
+		prediction := n.Predict(n.meanVector(t.NumFeatures), t.Config)
+		out.Prediction = &prediction
+		if n.DriftDetector != nil {
+			out.Drift = &DriftStatus{
+				Width: n.DriftDetector.width,
+				Mean:  n.DriftDetector.Estimate(),
+			}
+		}
+		return out
+	}
+
+	feature := n.SplitFeature
+	threshold := n.Threshold
+	out.Feature = &feature
+	out.Threshold = &threshold
+	if feature == ObliqueFeature {
+		out.Oblique = n.Oblique
+		out.FeatureName = n.Oblique.Expression(names)
+	} else {
+		out.FeatureName = featureName(names, feature)
+	}
+
+	out.Left = t.exportNode(n.Left, names, id, e)
+	out.Right = t.exportNode(n.Right, names, id, e)
+	out.Pos = out.Left.Pos + out.Right.Pos
+	out.Neg = out.Left.Neg + out.Right.Neg
+	return out
+}
+
+// meanVector returns the weighted mean of the feature values seen by n.
+// Features the node does not observe are zero.
+func (n *Node) meanVector(numFeatures int) features.FeatureVector {
+	fv := make(features.FeatureVector, numFeatures)
+	for i, fs := range n.FeatureStats {
+		if w := fs.PosDist.N + fs.NegDist.N; i < numFeatures && w > 0 {
+			fv[i] = (fs.PosDist.N*fs.PosDist.Mean + fs.NegDist.N*fs.NegDist.Mean) / w
+		}
+	}
+	return fv
+}
+
+func featureName(names []string, i int) string {
+	if i >= 0 && i < len(names) && names[i] != "" {
+		return names[i]
+	}
+	return fmt.Sprintf("x[%d]", i)
+}
+
+// Expression renders the model's margin as a readable formula, e.g.
+// "0.83*cpu + 0.61*mem - 0.7".
+func (m *LinearModel) Expression(names []string) string {
+	var b strings.Builder
+	for j, i := range m.Features {
+		w := m.Weights[j]
+		switch {
+		case j == 0:
+			fmt.Fprintf(&b, "%.3g*%s", w, featureName(names, i))
+		case w < 0:
+			fmt.Fprintf(&b, " - %.3g*%s", -w, featureName(names, i))
+		default:
+			fmt.Fprintf(&b, " + %.3g*%s", w, featureName(names, i))
+		}
+	}
+	if m.Bias < 0 {
+		fmt.Fprintf(&b, " - %.3g", -m.Bias)
+	} else {
+		fmt.Fprintf(&b, " + %.3g", m.Bias)
+	}
+	return b.String()
+}
+
+// WriteDOT renders the tree as a Graphviz digraph.
+func (t *Tree) WriteDOT(w io.Writer, names []string) error {
+	return WriteForestDOT(w, []*Tree{t}, names)
+}
+
+// WriteForestDOT renders several trees as a single Graphviz digraph with one
+// cluster per tree.
+func WriteForestDOT(w io.Writer, trees []*Tree, names []string) error {
+	exports := make([]*TreeExport, len(trees))
+	for i, t := range trees {
+		if t != nil {
+			e := t.Export(names)
+			exports[i] = &e
+		}
+	}
+	return WriteExportsDOT(w, exports)
+}
+
+// WriteExportsDOT renders tree exports as a single Graphviz digraph with one
+// cluster per export. Nil entries are skipped but keep their index.
+func WriteExportsDOT(w io.Writer, exports []*TreeExport) error {
+	var b strings.Builder
+	b.WriteString("digraph forest {\n")
+	b.WriteString("\tnode [shape=box, fontname=\"Helvetica\"];\n")
+
+	for i, e := range exports {
+		if e == nil {
+			continue
+		}
+		fmt.Fprintf(&b, "\tsubgraph cluster_%d {\n", i)
+		fmt.Fprintf(&b, "\t\tlabel=\"tree %d\";\n", i)
+
+		if e.Root == nil {
+			fmt.Fprintf(&b, "\t\tt%d_empty [label=\"empty\", style=dashed];\n", i)
+		} else {
+			writeDOTNode(&b, i, e.Root)
+		}
+		b.WriteString("\t}\n")
+	}
+
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeDOTNode(b *strings.Builder, tree int, n *NodeExport) {
+	var lines []string
+	if n.Leaf {
+		lines = append(lines,
+			fmt.Sprintf("p=%.3f", *n.Prediction),
+			fmt.Sprintf("pos=%g neg=%g", n.Pos, n.Neg),
+		)
+		if n.Drift != nil {
+			lines = append(lines, fmt.Sprintf("drift w=%d mean=%.3f", n.Drift.Width, n.Drift.Mean))
+		}
+	} else {
+		lines = append(lines,
+			fmt.Sprintf("%s <= %.4g", n.FeatureName, *n.Threshold),
+			fmt.Sprintf("n=%g", n.Pos+n.Neg),
+		)
+	}
+
+	fmt.Fprintf(b, "\t\tt%d_n%d [label=\"%s\"];\n", tree, n.ID, dotLabel(lines))
+	if n.Leaf {
+		return
+	}
+	fmt.Fprintf(b, "\t\tt%d_n%d -> t%d_n%d [label=\"yes\"];\n", tree, n.ID, tree, n.Left.ID)
+	fmt.Fprintf(b, "\t\tt%d_n%d -> t%d_n%d [label=\"no\"];\n", tree, n.ID, tree, n.Right.ID)
+	writeDOTNode(b, tree, n.Left)
+	writeDOTNode(b, tree, n.Right)
+}
+
+// dotLabel escapes lines for use inside a quoted DOT label.
+func dotLabel(lines []string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ")
+	for i, l := range lines {
+		lines[i] = r.Replace(l)
+	}
+	return strings.Join(lines, `\n`)
+}
@@ -0,0 +1,107 @@
+package forest
+
+import (
+	"math"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+// LeafPrediction selects how a leaf turns its statistics into a probability.
+type LeafPrediction int
+
+const (
+	// LeafMajorityClass predicts the observed frequency of the positive
+	// class at the leaf (Stats.Prob).
+	LeafMajorityClass LeafPrediction = iota
+	// LeafNaiveBayes predicts with a Gaussian naive Bayes model built from
+	// the leaf's per-feature attribute observers.
+	LeafNaiveBayes
+	// LeafNBAdaptive uses whichever of majority class and naive Bayes has
+	// classified more of the leaf's training samples correctly.
+	LeafNBAdaptive
+	// LeafLogistic predicts with an online logistic regression trained by
+	// SGD over the leaf's features. Leaves created by a split start from a
+	// copy of their parent's model.
+	LeafLogistic
+)
+
+// minVariance guards naive Bayes likelihoods against degenerate features.
+const minVariance = 1e-6
+
+// gaussian is an online weighted mean / variance estimator (West's
+// weighted variant of Welford's algorithm).
+type gaussian struct {
+	N    float64
+	Mean float64
+	M2   float64
+}
+
+func (g *gaussian) Update(x, weight float64) {
+	if weight <= 0 {
+		return
+	}
+	g.N += weight
+	d := x - g.Mean
+	g.Mean += weight * d / g.N
+	g.M2 += weight * d * (x - g.Mean)
+}
+
+// scale multiplies the weight of all observations by k, keeping the mean
+// estimate unchanged.
+func (g *gaussian) scale(k float64) {
+	g.N *= k
+	g.M2 *= k
+}
+
+func (g *gaussian) Variance() float64 {
+	if g.N < 2 {
+		return minVariance
+	}
+	v := g.M2 / (g.N - 1)
+	if v < minVariance {
+		return minVariance
+	}
+	return v
+}
+
+// LogPdf returns the log density of x under the estimated normal distribution.
+func (g *gaussian) LogPdf(x float64) float64 {
+	v := g.Variance()
+	d := x - g.Mean
+	return -0.5*math.Log(2*math.Pi*v) - d*d/(2*v)
+}
+
+// naiveBayes returns the Gaussian naive Bayes estimate of the positive class
+// probability at a leaf.
+func (n *Node) naiveBayes(fv features.FeatureVector) float64 {
+	if n.Stats.Pos == 0 || n.Stats.Neg == 0 || len(n.FeatureStats) == 0 {
+		return n.Stats.Prob()
+	}
+
+	total := n.Stats.Total()
+	logPos := math.Log((n.Stats.Pos + 1) / (total + 2))
+	logNeg := math.Log((n.Stats.Neg + 1) / (total + 2))
+
+	// Sum in feature order to keep predictions bit-for-bit reproducible.
+	for i, x := range fv {
+		fs, ok := n.FeatureStats[i]
+		if !ok {
+			continue
+		}
+		logPos += fs.PosDist.LogPdf(x)
+		logNeg += fs.NegDist.LogPdf(x)
+	}
+	return 1.0 / (1.0 + math.Exp(logNeg-logPos))
+}
+
+// trackLeafAccuracy records whether the majority-class and naive Bayes
+// predictors would have classified the sample correctly. It must be called
+// before the leaf statistics are updated with the sample.
+func (n *Node) trackLeafAccuracy(fv features.FeatureVector, label bool, weight float64) {
+	if (n.Stats.Prob() >= 0.5) == label {
+		n.MCCorrect += weight
+	}
+	if (n.naiveBayes(fv) >= 0.5) == label {
+		n.NBCorrect += weight
+	}
+}
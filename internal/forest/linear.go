@@ -0,0 +1,125 @@
+package forest
+
+import (
+	"math"
+
+	"github.com/kudmo/onlinerf/api/features"
+)
+
+// ObliqueFeature is the SplitFeature of nodes that split on a linear
+// combination of features rather than a single one (see
+// TreeConfig.ObliqueSplits).
+const ObliqueFeature = -1
+
+// defaultLearningRate is the SGD step size of linear models when
+// TreeConfig.ObliqueLearningRate or LeafLearningRate is not set.
+const defaultLearningRate = 0.1
+
+// LinearModel is an online logistic regression over a subset of features,
+// trained by stochastic gradient descent on the log loss.
+type LinearModel struct {
+	Features []int     `json:"features"`
+	Weights  []float64 `json:"weights"`
+	Bias     float64   `json:"bias"`
+}
+
+func newLinearModel(features []int) *LinearModel {
+	return &LinearModel{
+		Features: features,
+		Weights:  make([]float64, len(features)),
+	}
+}
+
+// Margin returns w·x + b. Samples with a margin <= 0 are routed left by an
+// oblique split.
+func (m *LinearModel) Margin(fv features.FeatureVector) float64 {
+	s := m.Bias
+	for j, i := range m.Features {
+		if i < len(fv) {
+			s += m.Weights[j] * fv[i]
+		}
+	}
+	return s
+}
+
+// Prob returns the model's probability of the positive class.
+func (m *LinearModel) Prob(fv features.FeatureVector) float64 {
+	return 1 / (1 + math.Exp(-m.Margin(fv)))
+}
+
+// Update takes one gradient step on the weighted log loss of the sample.
+func (m *LinearModel) Update(fv features.FeatureVector, label bool, weight, learningRate float64) {
+	target := 0.0
+	if label {
+		target = 1
+	}
+	g := learningRate * weight * (target - m.Prob(fv))
+	for j, i := range m.Features {
+		if i < len(fv) {
+			m.Weights[j] += g * fv[i]
+		}
+	}
+	m.Bias += g
+}
+
+// clone returns a deep copy of the model.
+func (m *LinearModel) clone() *LinearModel {
+	return &LinearModel{
+		Features: append([]int(nil), m.Features...),
+		Weights:  append([]float64(nil), m.Weights...),
+		Bias:     m.Bias,
+	}
+}
+
+// Contributions returns |w_i·x_i| for every feature of the model, i.e. how
+// strongly each feature drives the margin for fv.
+func (m *LinearModel) Contributions(fv features.FeatureVector) []float64 {
+	c := make([]float64, len(m.Features))
+	for j, i := range m.Features {
+		if i < len(fv) {
+			c[j] = math.Abs(m.Weights[j] * fv[i])
+		}
+	}
+	return c
+}
+
+func (c TreeConfig) obliqueLearningRate() float64 {
+	if c.ObliqueLearningRate > 0 {
+		return c.ObliqueLearningRate
+	}
+	return defaultLearningRate
+}
+
+func (c TreeConfig) leafLearningRate() float64 {
+	if c.LeafLearningRate > 0 {
+		return c.LeafLearningRate
+	}
+	return defaultLearningRate
+}
+
+// obliqueFeatures picks the features of a new oblique candidate at leaf n:
+// all of the leaf's features, or a random subset of ObliqueFeatures of them.
+func (t *Tree) obliqueFeatures(n *Node) []int {
+	idx := sortedFeatures(n.FeatureStats)
+	k := t.Config.ObliqueFeatures
+	if k <= 0 || k >= len(idx) {
+		return idx
+	}
+	sub := t.Rand.Subset(len(idx), k)
+	for j, s := range sub {
+		sub[j] = idx[s]
+	}
+	return sub
+}
+
+// updateOblique trains the leaf's oblique split candidate. The class counts
+// on both sides of the candidate's boundary are recorded before the model
+// learns from the sample, so they measure its prequential quality.
+func (n *Node) updateOblique(fv features.FeatureVector, label bool, weight float64, t *Tree) {
+	if n.Oblique == nil {
+		n.Oblique = newLinearModel(t.obliqueFeatures(n))
+		n.ObliqueStat = &FeatureStat{}
+	}
+	n.ObliqueStat.Update(n.Oblique.Margin(fv), label, weight)
+	n.Oblique.Update(fv, label, weight, t.Config.obliqueLearningRate())
+}
@@ -2,6 +2,7 @@ package forest
 
 import (
 	"math"
+	"sort"
 
 	"github.com/kudmo/onlinerf/api/features"
 )
@@ -23,17 +24,52 @@ type Node struct {
 	Left  *Node
 	Right *Node
 
+	// MCCorrect and NBCorrect are the weights of the training samples that
+	// the majority-class and naive Bayes predictors classified correctly at
+	// this leaf (maintained only in LeafNBAdaptive mode).
+	MCCorrect float64
+	NBCorrect float64
+
+	// LastSplitAttempt is the leaf's sample weight at its last split attempt.
+	LastSplitAttempt float64
+
+	// LastUpdate is the tree's sample index at the node's last update; it
+	// tells how much decay is pending when TreeConfig.FadingFactor is set.
+	LastUpdate uint64
+
+	// Model is the leaf's logistic regression in LeafLogistic mode.
+	Model *LinearModel
+
+	// Oblique is the split function of an oblique internal node (with
+	// SplitFeature == ObliqueFeature) or, at a leaf, the oblique split
+	// candidate being trained. ObliqueStat holds the class counts on both
+	// sides of its decision boundary.
+	Oblique     *LinearModel
+	ObliqueStat *FeatureStat
+
 	// DRIFT DETECTION
 	DriftDetector *DriftDetector
 }
 
 func NewLeaf(depth int, numFeatures int, bootstrap features.FeatureVector) *Node {
-	fs := make(map[int]*FeatureStat)
+	idx := make([]int, numFeatures)
+	for i := range idx {
+		idx[i] = i
+	}
+	return newLeafFor(depth, idx, bootstrap)
+}
 
-	for i := 0; i < numFeatures; i++ {
-		fs[i] = &FeatureStat{
-			Threshold: bootstrap[i], // bootstrap median-like
+// newLeafFor creates a leaf that observes only the features in idx. Candidate
+// thresholds are taken from bootstrap, or are zero if bootstrap is nil.
+func newLeafFor(depth int, idx []int, bootstrap features.FeatureVector) *Node {
+	fs := make(map[int]*FeatureStat, len(idx))
+
+	for _, i := range idx {
+		var threshold float64
+		if bootstrap != nil {
+			threshold = bootstrap[i] // bootstrap median-like
 		}
+		fs[i] = &FeatureStat{Threshold: threshold}
 	}
 
 	return &Node{
@@ -43,35 +79,68 @@ func NewLeaf(depth int, numFeatures int, bootstrap features.FeatureVector) *Node
 	}
 }
 
-// Predict returns the probability estimate at this node.
-func (n *Node) Predict(fv features.FeatureVector) float64 {
+// Predict returns the probability estimate at this node according to
+// cfg.LeafPrediction.
+func (n *Node) Predict(fv features.FeatureVector, cfg TreeConfig) float64 {
 	if !n.IsLeaf {
 		panic("Predict should only be called on leaf nodes")
 	}
+	switch cfg.LeafPrediction {
+	case LeafNaiveBayes:
+		return n.naiveBayes(fv)
+	case LeafNBAdaptive:
+		if n.NBCorrect > n.MCCorrect {
+			return n.naiveBayes(fv)
+		}
+	case LeafLogistic:
+		if n.Model != nil {
+			return n.Model.Prob(fv)
+		}
+	}
 	return n.Stats.Prob()
 }
 
 func (n *Node) ChooseChild(fv features.FeatureVector) *Node {
+	if n.SplitFeature == ObliqueFeature {
+		if n.Oblique.Margin(fv) <= n.Threshold {
+			return n.Left
+		}
+		return n.Right
+	}
 	if fv[n.SplitFeature] <= n.Threshold {
 		return n.Left
 	}
 	return n.Right
 }
 
-func (n *Node) Update(fv features.FeatureVector, label bool, cfg TreeConfig) {
+// Update trains the subtree rooted at n with a sample of the given weight.
+// Weights act like repeated samples, e.g. for online bagging. t is the tree
+// the node belongs to.
+func (n *Node) Update(fv features.FeatureVector, label bool, weight float64, t *Tree) {
+	cfg := t.Config
+	n.decay(t.Samples, cfg)
+
+	if !n.IsLeaf && cfg.SplitMode == SplitEFDT {
+		n.updateInternal(fv, label, weight, cfg)
+		if n.IsLeaf {
+			// The split was pruned; the sample is already counted here.
+			return
+		}
+	}
+
 	if n.IsLeaf {
 		// 1. Update label statistics at this leaf.
-		n.Stats.Update(label)
+		if cfg.LeafPrediction == LeafNBAdaptive {
+			n.trackLeafAccuracy(fv, label, weight)
+		}
+		n.Stats.Update(label, weight)
 
 		// 2. Check for concept drift if a detector is attached.
 		if n.DriftDetector != nil {
 			drift := n.DriftDetector.Add(label)
 			if drift {
 				// Drift detected — reset this leaf and its statistics.
-				n.Left = nil
-				n.Right = nil
-				n.IsLeaf = true
-				n.Stats = Stats{}
+				n.resetLeaf()
 
 				// Restart the detector for the new distribution.
 				if cfg.UseDriftDetection {
@@ -81,11 +150,22 @@ func (n *Node) Update(fv features.FeatureVector, label bool, cfg TreeConfig) {
 			}
 		}
 		for i, v := range fv {
-			n.FeatureStats[i].Update(v, label)
+			if fs, ok := n.FeatureStats[i]; ok {
+				fs.Update(v, label, weight)
+			}
+		}
+		if cfg.ObliqueSplits {
+			n.updateOblique(fv, label, weight, t)
+		}
+		if cfg.LeafPrediction == LeafLogistic {
+			if n.Model == nil {
+				n.Model = newLinearModel(sortedFeatures(n.FeatureStats))
+			}
+			n.Model.Update(fv, label, weight, cfg.leafLearningRate())
 		}
 
 		// 3. Check if the node is eligible for splitting.
-		if n.Stats.Total() < cfg.MinSamplesPerLeaf {
+		if n.Stats.Total() < float64(cfg.MinSamplesPerLeaf) {
 			return
 		}
 
@@ -93,13 +173,56 @@ func (n *Node) Update(fv features.FeatureVector, label bool, cfg TreeConfig) {
 			return
 		}
 
+		if n.Stats.Total()-n.LastSplitAttempt < float64(cfg.GracePeriod) {
+			return
+		}
+		n.LastSplitAttempt = n.Stats.Total()
+
 		n.trySplit(cfg)
 		return
 	}
 
 	// Non-leaf node — descend into the chosen child.
 	child := n.ChooseChild(fv)
-	child.Update(fv, label, cfg)
+	child.Update(fv, label, weight, t)
+}
+
+// resetLeaf discards everything the leaf has learned after a concept drift.
+// Feature statistics are rebuilt empty around the same candidate thresholds
+// so that they stay consistent with the fresh class counts.
+func (n *Node) resetLeaf() {
+	n.Left = nil
+	n.Right = nil
+	n.IsLeaf = true
+	n.Stats = Stats{}
+	for i, fs := range n.FeatureStats {
+		n.FeatureStats[i] = &FeatureStat{Threshold: fs.Threshold}
+	}
+	n.MCCorrect = 0
+	n.NBCorrect = 0
+	n.LastSplitAttempt = 0
+	n.Oblique = nil
+	n.ObliqueStat = nil
+	n.Model = nil
+}
+
+// decay applies the fading factor for the samples seen by the tree since
+// the node's last update.
+func (n *Node) decay(tick uint64, cfg TreeConfig) {
+	if cfg.fading() && tick > n.LastUpdate {
+		k := math.Pow(cfg.FadingFactor, float64(tick-n.LastUpdate))
+		n.Stats.scale(k)
+		for _, fs := range n.FeatureStats {
+			fs.scale(k)
+		}
+		if n.ObliqueStat != nil {
+			n.ObliqueStat.scale(k)
+		}
+		n.MCCorrect *= k
+		n.NBCorrect *= k
+		n.LastSplitAttempt *= k
+	}
+	n.LastUpdate = tick
 }
 
 func (n *Node) trySplit(cfg TreeConfig) {
@@ -107,50 +230,121 @@ func (n *Node) trySplit(cfg TreeConfig) {
 	// count and increment on split).
 
 	total := n.Stats.Total()
-	parentPos := n.Stats.Pos
-	parentNeg := n.Stats.Neg
+	parent := ClassCounts{Pos: n.Stats.Pos, Neg: n.Stats.Neg}
+	criterion := cfg.criterion()
 
-	var bestFeature int = -1
+	var bestFeature int
+	var found bool
 	var bestGain float64 = -1
 	var secondBest float64 = -1
 
-	for i, fs := range n.FeatureStats {
-		gain := fs.GiniGain(parentPos, parentNeg)
+	consider := func(feature int, stat *FeatureStat) {
+		left, right := stat.Counts()
+		gain := criterion.Merit(parent, left, right)
 
 		if gain > bestGain {
 			secondBest = bestGain
 			bestGain = gain
-			bestFeature = i
+			bestFeature = feature
+			found = true
 		} else if gain > secondBest {
 			secondBest = gain
 		}
 	}
 
-	if bestFeature == -1 {
+	// Iterate in feature order so that ties are broken deterministically.
+	for _, i := range sortedFeatures(n.FeatureStats) {
+		consider(i, n.FeatureStats[i])
+	}
+	if cfg.ObliqueSplits && n.ObliqueStat != nil {
+		consider(ObliqueFeature, n.ObliqueStat)
+	}
+
+	if !found || bestGain < cfg.MinSplitGain {
 		return
 	}
 
 	// Hoeffding bound
-	R := 1.0
-	epsilon := math.Sqrt(
-		(R * R * math.Log(1.0/cfg.HoeffdingSplitDelta)) /
-			(2.0 * float64(total)),
-	)
+	epsilon := hoeffdingBound(criterion.Range(parent), cfg.HoeffdingSplitDelta, total)
+
+	// EFDT only needs the best split to beat not splitting; a poor choice
+	// can be revised later by reevaluate.
+	if cfg.SplitMode == SplitEFDT {
+		if bestGain > epsilon || epsilon < cfg.TieThreshold {
+			n.splitOn(bestFeature, cfg)
+		}
+		return
+	}
 
-	if bestGain-secondBest > epsilon {
+	// Pre-pruning: the "null" split (keeping the leaf) has merit 0 and
+	// competes like any other candidate; the best split must beat it by ε.
+	if cfg.PrePrune {
+		if secondBest < 0 {
+			secondBest = 0
+		}
+		if bestGain <= epsilon {
+			return
+		}
+	}
+
+	if bestGain-secondBest > epsilon || epsilon < cfg.TieThreshold {
+		n.splitOn(bestFeature, cfg)
+	}
+}
 
-		bestFS := n.FeatureStats[bestFeature]
+// splitOn turns n into an internal node splitting on feature (or on the
+// oblique candidate for ObliqueFeature) with fresh leaves as children. EFDT
+// keeps the node's statistics and leaf model for re-evaluation, so a pruned
+// node or a replaced split starts from a trained model.
+func (n *Node) splitOn(feature int, cfg TreeConfig) {
+	n.IsLeaf = false
+	n.SplitFeature = feature
+	if feature == ObliqueFeature {
+		// The candidate becomes the node's fixed split function.
+		n.Threshold = 0
+	} else {
+		n.Threshold = n.FeatureStats[feature].Threshold
+		n.Oblique = nil
+		n.ObliqueStat = nil
+	}
 
-		n.IsLeaf = false
-		n.SplitFeature = bestFeature
-		n.Threshold = bestFS.Threshold
+	// Children observe the same features as their parent.
+	idx := sortedFeatures(n.FeatureStats)
 
-		numFeatures := len(n.FeatureStats)
+	n.Left = newLeafFor(n.Depth+1, idx, nil)
+	n.Right = newLeafFor(n.Depth+1, idx, nil)
+	n.Left.LastUpdate = n.LastUpdate
+	n.Right.LastUpdate = n.LastUpdate
+	n.Left.DriftDetector = cfg.newDriftDetector()
+	n.Right.DriftDetector = cfg.newDriftDetector()
 
-		n.Left = NewLeaf(n.Depth+1, numFeatures, make(features.FeatureVector, numFeatures))
-		n.Right = NewLeaf(n.Depth+1, numFeatures, make(features.FeatureVector, numFeatures))
+	// Warm-start the children's leaf models from the parent's.
+	if n.Model != nil {
+		n.Left.Model = n.Model.clone()
+		n.Right.Model = n.Model.clone()
+	}
 
+	if cfg.SplitMode != SplitEFDT {
 		// очищаем статистику текущего листа
 		n.FeatureStats = nil
+		n.ObliqueStat = nil
+		n.Model = nil
+	}
+}
+
+// hoeffdingBound returns ε such that the true mean of a variable with the
+// given range lies within ε of its mean over n samples with probability
+// 1-delta.
+func hoeffdingBound(r, delta, n float64) float64 {
+	return math.Sqrt(r * r * math.Log(1.0/delta) / (2.0 * n))
+}
+
+// sortedFeatures returns the feature indices of stats in increasing order.
+func sortedFeatures(stats map[int]*FeatureStat) []int {
+	idx := make([]int, 0, len(stats))
+	for i := range stats {
+		idx = append(idx, i)
 	}
+	sort.Ints(idx)
+	return idx
 }
@@ -0,0 +1,47 @@
+package forest
+
+import "github.com/kudmo/onlinerf/api/features"
+
+// Decision is a single routing decision taken while descending a tree.
+type Decision struct {
+	Feature   int
+	Threshold float64
+	// Value is the compared value: fv[Feature], or the margin of Oblique
+	// for oblique splits.
+	Value float64
+	// Oblique is the split function of oblique splits (Feature ==
+	// ObliqueFeature) and nil otherwise.
+	Oblique *LinearModel
+	// Left reports whether the sample was routed to the left child
+	// (Value <= Threshold).
+	Left bool
+}
+
+// DecisionPath returns the sequence of decisions taken by ChooseChild for fv
+// and the leaf where the sample ends up. The leaf is nil if the tree has not
+// been initialized yet.
+func (t *Tree) DecisionPath(fv features.FeatureVector) ([]Decision, *Node) {
+	if t.Root == nil {
+		return nil, nil
+	}
+
+	var path []Decision
+	node := t.Root
+	for !node.IsLeaf {
+		next := node.ChooseChild(fv)
+		d := Decision{
+			Feature:   node.SplitFeature,
+			Threshold: node.Threshold,
+			Left:      next == node.Left,
+		}
+		if node.SplitFeature == ObliqueFeature {
+			d.Oblique = node.Oblique
+			d.Value = node.Oblique.Margin(fv)
+		} else {
+			d.Value = fv[node.SplitFeature]
+		}
+		path = append(path, d)
+		node = next
+	}
+	return path, node
+}
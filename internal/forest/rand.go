@@ -0,0 +1,118 @@
+package forest
+
+import (
+	"math"
+	"sort"
+)
+
+// RNG is a small splitmix64 pseudo-random generator. Its whole state is a
+// single uint64, which makes it cheap to snapshot and restore exactly.
+// An RNG is not safe for concurrent use.
+type RNG struct {
+	state uint64
+}
+
+// NewRNG creates a generator with the given seed.
+func NewRNG(seed uint64) *RNG {
+	return &RNG{state: seed}
+}
+
+// DeriveSeed derives the seed of an independent stream (e.g. one per tree)
+// from a base seed.
+func DeriveSeed(seed int64, stream int) uint64 {
+	r := NewRNG(uint64(seed) ^ (uint64(stream)+1)*0x9e3779b97f4a7c15)
+	r.Uint64()
+	return r.Uint64()
+}
+
+// State returns the current generator state.
+func (r *RNG) State() uint64 {
+	return r.state
+}
+
+// SetState restores a state previously returned by State.
+func (r *RNG) SetState(state uint64) {
+	r.state = state
+}
+
+// Uint64 returns a pseudo-random 64-bit value.
+func (r *RNG) Uint64() uint64 {
+	r.state += 0x9e3779b97f4a7c15
+	z := r.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Float64 returns a pseudo-random number in [0,1).
+func (r *RNG) Float64() float64 {
+	return float64(r.Uint64()>>11) / (1 << 53)
+}
+
+// Intn returns a pseudo-random number in [0,n). It panics if n <= 0.
+func (r *RNG) Intn(n int) int {
+	if n <= 0 {
+		panic("forest: invalid argument to Intn")
+	}
+	return int(r.Uint64() % uint64(n))
+}
+
+// Subset returns k distinct indices drawn uniformly from [0,n), in
+// increasing order. k is clamped to [0,n].
+func (r *RNG) Subset(n, k int) []int {
+	if k > n {
+		k = n
+	}
+	if k < 0 {
+		k = 0
+	}
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+	// Partial Fisher-Yates shuffle.
+	for i := 0; i < k; i++ {
+		j := i + r.Intn(n-i)
+		perm[i], perm[j] = perm[j], perm[i]
+	}
+	subset := perm[:k]
+	sort.Ints(subset)
+	return subset
+}
+
+// NormFloat64 returns a standard normally distributed number (Box-Muller).
+func (r *RNG) NormFloat64() float64 {
+	u := 1 - r.Float64() // (0,1]
+	v := r.Float64()
+	return math.Sqrt(-2*math.Log(u)) * math.Cos(2*math.Pi*v)
+}
+
+// poissonNormalCutoff is the mean above which Poisson falls back to a normal
+// approximation instead of Knuth's algorithm, whose cost grows with lambda.
+const poissonNormalCutoff = 30
+
+// Poisson returns a sample from a Poisson distribution with mean lambda.
+// Small means (as used in online bagging) use Knuth's algorithm; large
+// means use a rounded normal approximation.
+func (r *RNG) Poisson(lambda float64) int {
+	if lambda <= 0 {
+		return 0
+	}
+	if lambda > poissonNormalCutoff {
+		k := math.Round(lambda + math.Sqrt(lambda)*r.NormFloat64())
+		if k < 0 {
+			return 0
+		}
+		return int(k)
+	}
+	l := math.Exp(-lambda)
+	k := 0
+	p := 1.0
+	for {
+		p *= r.Float64()
+		if p <= l {
+			return k
+		}
+		k++
+	}
+}
@@ -0,0 +1,228 @@
+package forest
+
+import "github.com/kudmo/onlinerf/api/features"
+
+// pathElement is a single entry of the feature path maintained by TreeSHAP.
+type pathElement struct {
+	feature      int
+	zeroFraction float64
+	oneFraction  float64
+	weight       float64
+}
+
+// SHAP computes per-feature contributions of fv to the tree's prediction
+// using the TreeSHAP algorithm (Lundberg et al., 2018).
+//
+// Node covers are the number of training samples observed by the leaves
+// below each node. The returned base value is the cover-weighted expected
+// prediction of the tree, so that base + sum(phi) == t.Predict(fv).
+//
+// Each oblique split is treated as a virtual feature of its own. Its value
+// is then shared among the split's features in proportion to |w_i·x_i|, so
+// the contributions still add up to the prediction.
+func (t *Tree) SHAP(fv features.FeatureVector) (phi []float64, base float64) {
+	phi = make([]float64, t.NumFeatures)
+	if t.Root == nil {
+		return phi, t.Predict(fv)
+	}
+
+	covers := make(map[*Node]float64)
+	nodeCover(t.Root, covers)
+
+	s := &shapState{fv: fv, cfg: t.Config, covers: covers, virtual: make(map[*Node]int)}
+	s.assignVirtual(t.Root, t.NumFeatures)
+	s.phi = make([]float64, t.NumFeatures+len(s.virtual))
+	base = s.expectedValue(t.Root)
+	s.recurse(t.Root, nil, 1, 1, -1)
+
+	copy(phi, s.phi)
+	for n, v := range s.virtual {
+		c := n.Oblique.Contributions(fv)
+		var total float64
+		for _, x := range c {
+			total += x
+		}
+		for j, i := range n.Oblique.Features {
+			share := 1 / float64(len(c))
+			if total > 0 {
+				share = c[j] / total
+			}
+			phi[i] += s.phi[v] * share
+		}
+	}
+	return phi, base
+}
+
+// assignVirtual gives every oblique split below n its own feature index,
+// starting at next.
+func (s *shapState) assignVirtual(n *Node, next int) int {
+	if n.IsLeaf {
+		return next
+	}
+	if n.SplitFeature == ObliqueFeature {
+		s.virtual[n] = next
+		next++
+	}
+	next = s.assignVirtual(n.Left, next)
+	return s.assignVirtual(n.Right, next)
+}
+
+// splitFeature returns the feature index of n's split for TreeSHAP.
+func (s *shapState) splitFeature(n *Node) int {
+	if v, ok := s.virtual[n]; ok {
+		return v
+	}
+	return n.SplitFeature
+}
+
+// nodeCover fills covers with the number of samples seen below n.
+func nodeCover(n *Node, covers map[*Node]float64) float64 {
+	var c float64
+	if n.IsLeaf {
+		c = n.Stats.Total()
+	} else {
+		c = nodeCover(n.Left, covers) + nodeCover(n.Right, covers)
+	}
+	covers[n] = c
+	return c
+}
+
+type shapState struct {
+	fv     features.FeatureVector
+	cfg    TreeConfig
+	covers map[*Node]float64
+	phi    []float64
+	// virtual maps oblique split nodes to their virtual feature index.
+	virtual map[*Node]int
+}
+
+// childFractions returns the share of n's cover going to the left and right
+// children. Nodes without any observed samples split their weight evenly.
+func (s *shapState) childFractions(n *Node) (left, right float64) {
+	total := s.covers[n]
+	if total == 0 {
+		return 0.5, 0.5
+	}
+	return s.covers[n.Left] / total, s.covers[n.Right] / total
+}
+
+func (s *shapState) expectedValue(n *Node) float64 {
+	if n.IsLeaf {
+		return n.Predict(s.fv, s.cfg)
+	}
+	l, r := s.childFractions(n)
+	return l*s.expectedValue(n.Left) + r*s.expectedValue(n.Right)
+}
+
+func (s *shapState) recurse(n *Node, parentPath []pathElement, zeroFraction, oneFraction float64, feature int) {
+	path := make([]pathElement, len(parentPath), len(parentPath)+1)
+	copy(path, parentPath)
+	path = extendPath(path, zeroFraction, oneFraction, feature)
+
+	if n.IsLeaf {
+		value := n.Predict(s.fv, s.cfg)
+		for i := 1; i < len(path); i++ {
+			w := unwoundPathSum(path, i)
+			el := path[i]
+			s.phi[el.feature] += w * (el.oneFraction - el.zeroFraction) * value
+		}
+		return
+	}
+
+	hot, cold := n.Left, n.Right
+	leftFrac, rightFrac := s.childFractions(n)
+	hotFrac, coldFrac := leftFrac, rightFrac
+	if n.ChooseChild(s.fv) == n.Right {
+		hot, cold = cold, hot
+		hotFrac, coldFrac = coldFrac, hotFrac
+	}
+
+	// If the split feature already appears on the path, undo its previous
+	// contribution so each feature is counted once.
+	split := s.splitFeature(n)
+	incomingZero, incomingOne := 1.0, 1.0
+	for i := 1; i < len(path); i++ {
+		if path[i].feature == split {
+			incomingZero = path[i].zeroFraction
+			incomingOne = path[i].oneFraction
+			path = unwindPath(path, i)
+			break
+		}
+	}
+
+	s.recurse(hot, path, hotFrac*incomingZero, incomingOne, split)
+	// A cold branch without cover contributes nothing; skipping it also
+	// avoids dividing by zero fractions further down.
+	if coldFrac*incomingZero != 0 {
+		s.recurse(cold, path, coldFrac*incomingZero, 0, split)
+	}
+}
+
+// extendPath appends a new feature to the path and updates the permutation
+// weights of all existing elements.
+func extendPath(path []pathElement, zeroFraction, oneFraction float64, feature int) []pathElement {
+	depth := len(path)
+	w := 0.0
+	if depth == 0 {
+		w = 1.0
+	}
+	path = append(path, pathElement{
+		feature:      feature,
+		zeroFraction: zeroFraction,
+		oneFraction:  oneFraction,
+		weight:       w,
+	})
+
+	for i := depth - 1; i >= 0; i-- {
+		path[i+1].weight += oneFraction * path[i].weight * float64(i+1) / float64(depth+1)
+		path[i].weight = zeroFraction * path[i].weight * float64(depth-i) / float64(depth+1)
+	}
+	return path
+}
+
+// unwindPath removes the element at index from the path, reversing the
+// effect of the corresponding extendPath call.
+func unwindPath(path []pathElement, index int) []pathElement {
+	depth := len(path) - 1
+	oneFraction := path[index].oneFraction
+	zeroFraction := path[index].zeroFraction
+	next := path[depth].weight
+
+	for i := depth - 1; i >= 0; i-- {
+		if oneFraction != 0 {
+			tmp := path[i].weight
+			path[i].weight = next * float64(depth+1) / (float64(i+1) * oneFraction)
+			next = tmp - path[i].weight*zeroFraction*float64(depth-i)/float64(depth+1)
+		} else {
+			path[i].weight = path[i].weight * float64(depth+1) / (zeroFraction * float64(depth-i))
+		}
+	}
+
+	for i := index; i < depth; i++ {
+		path[i].feature = path[i+1].feature
+		path[i].zeroFraction = path[i+1].zeroFraction
+		path[i].oneFraction = path[i+1].oneFraction
+	}
+	return path[:depth]
+}
+
+// unwoundPathSum returns the total permutation weight of the path as if the
+// element at index had been removed.
+func unwoundPathSum(path []pathElement, index int) float64 {
+	depth := len(path) - 1
+	oneFraction := path[index].oneFraction
+	zeroFraction := path[index].zeroFraction
+	next := path[depth].weight
+	total := 0.0
+
+	for i := depth - 1; i >= 0; i-- {
+		if oneFraction != 0 {
+			tmp := next * float64(depth+1) / (float64(i+1) * oneFraction)
+			total += tmp
+			next = path[i].weight - tmp*zeroFraction*float64(depth-i)/float64(depth+1)
+		} else if zeroFraction != 0 {
+			total += path[i].weight / zeroFraction / (float64(depth-i) / float64(depth+1))
+		}
+	}
+	return total
+}
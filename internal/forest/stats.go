@@ -1,19 +1,27 @@
 package forest
 
+// Stats holds the (possibly weighted) label counts observed at a node.
 type Stats struct {
-	Pos int
-	Neg int
+	Pos float64
+	Neg float64
 }
 
-func (s *Stats) Update(label bool) {
+// Update adds a sample with the given weight.
+func (s *Stats) Update(label bool, weight float64) {
 	if label {
-		s.Pos++
+		s.Pos += weight
 	} else {
-		s.Neg++
+		s.Neg += weight
 	}
 }
 
-func (s *Stats) Total() int {
+// scale multiplies all counts by k.
+func (s *Stats) scale(k float64) {
+	s.Pos *= k
+	s.Neg *= k
+}
+
+func (s *Stats) Total() float64 {
 	return s.Pos + s.Neg
 }
 
@@ -22,60 +30,59 @@ func (s *Stats) Prob() float64 {
 	if total == 0 {
 		return 0.5
 	}
-	return float64(s.Pos) / float64(total)
-}
-
-func gini(pos, neg int) float64 {
-	total := pos + neg
-	if total == 0 {
-		return 0
-	}
-	p := float64(pos) / float64(total)
-	return 1 - p*p - (1-p)*(1-p)
+	return s.Pos / total
 }
 
 type FeatureStat struct {
 	Threshold float64
 
-	LeftPos  int
-	LeftNeg  int
-	RightPos int
-	RightNeg int
+	LeftPos  float64
+	LeftNeg  float64
+	RightPos float64
+	RightNeg float64
+
+	// PosDist and NegDist observe the feature's distribution per class and
+	// back naive Bayes leaf predictions.
+	PosDist gaussian
+	NegDist gaussian
 }
 
-func (f *FeatureStat) Update(value float64, label bool) {
+// Update adds a sample with the given weight.
+func (f *FeatureStat) Update(value float64, label bool, weight float64) {
+	if label {
+		f.PosDist.Update(value, weight)
+	} else {
+		f.NegDist.Update(value, weight)
+	}
+
 	if value <= f.Threshold {
 		if label {
-			f.LeftPos++
+			f.LeftPos += weight
 		} else {
-			f.LeftNeg++
+			f.LeftNeg += weight
 		}
 	} else {
 		if label {
-			f.RightPos++
+			f.RightPos += weight
 		} else {
-			f.RightNeg++
+			f.RightNeg += weight
 		}
 	}
 }
 
-func (f *FeatureStat) GiniGain(parentPos, parentNeg int) float64 {
-	parentGini := gini(parentPos, parentNeg)
-
-	leftTotal := f.LeftPos + f.LeftNeg
-	rightTotal := f.RightPos + f.RightNeg
-	total := leftTotal + rightTotal
-
-	if total == 0 {
-		return 0
-	}
-
-	leftWeight := float64(leftTotal) / float64(total)
-	rightWeight := float64(rightTotal) / float64(total)
-
-	weighted :=
-		leftWeight*gini(f.LeftPos, f.LeftNeg) +
-			rightWeight*gini(f.RightPos, f.RightNeg)
+// scale multiplies all counts by k.
+func (f *FeatureStat) scale(k float64) {
+	f.LeftPos *= k
+	f.LeftNeg *= k
+	f.RightPos *= k
+	f.RightNeg *= k
+	f.PosDist.scale(k)
+	f.NegDist.scale(k)
+}
 
-	return parentGini - weighted
+// Counts returns the class counts on both sides of the candidate threshold.
+func (f *FeatureStat) Counts() (left, right ClassCounts) {
+	left = ClassCounts{Pos: f.LeftPos, Neg: f.LeftNeg}
+	right = ClassCounts{Pos: f.RightPos, Neg: f.RightNeg}
+	return left, right
 }
@@ -10,6 +10,98 @@ type TreeConfig struct {
 	MinSamplesPerLeaf   int
 	UseDriftDetection   bool
 	DriftAlpha          float64
+	LeafPrediction      LeafPrediction
+
+	// LeafLearningRate is the SGD step size of LeafLogistic leaf models.
+	// Defaults to 0.1.
+	LeafLearningRate float64
+
+	// SplitCriterion scores candidate splits. Defaults to GiniCriterion.
+	SplitCriterion SplitCriterion
+
+	// TieThreshold is the VFDT tie-breaking threshold tau: once the
+	// Hoeffding bound drops below it, the best candidate is used even if
+	// the runner-up is almost as good. Zero disables tie breaking.
+	TieThreshold float64
+
+	// GracePeriod is the number of samples a leaf must receive between
+	// two split attempts. Zero or one evaluates on every sample.
+	GracePeriod int
+
+	// PrePrune adds the "no split" null candidate with merit 0, so a leaf
+	// only splits if its best candidate beats not splitting by ε.
+	PrePrune bool
+
+	// MinSplitGain is the minimum merit the best candidate must reach for
+	// a split to be considered.
+	MinSplitGain float64
+
+	// FadingFactor, if in (0, 1), exponentially decays the statistics of
+	// every node: counts are multiplied by FadingFactor once per sample the
+	// tree has seen since the node was last updated. The decay is applied
+	// lazily when a node is updated. Zero or one keeps counts forever.
+	FadingFactor float64
+
+	// SplitMode selects VFDT (default) or EFDT split decisions.
+	SplitMode SplitMode
+
+	// ReevaluationPeriod is the number of samples an internal node receives
+	// between re-evaluations of its split in EFDT mode. Zero uses
+	// GracePeriod.
+	ReevaluationPeriod int
+
+	// ObliqueSplits lets leaves learn a small online logistic model and
+	// split on its output (w·x + b <= 0) when that beats every
+	// axis-aligned candidate.
+	ObliqueSplits bool
+
+	// ObliqueFeatures is the number of randomly chosen features used by an
+	// oblique candidate. Zero uses all of the leaf's features.
+	ObliqueFeatures int
+
+	// ObliqueLearningRate is the SGD step size of oblique candidates.
+	// Defaults to 0.1.
+	ObliqueLearningRate float64
+
+	// FeatureSubset, if non-nil, restricts the tree to the listed feature
+	// indices (random subspaces); other features are ignored. Nil uses all
+	// features.
+	FeatureSubset []int
+
+	// Seed initializes the tree's random number generator.
+	Seed uint64
+}
+
+func (c TreeConfig) criterion() SplitCriterion {
+	if c.SplitCriterion == nil {
+		return GiniCriterion{}
+	}
+	return c.SplitCriterion
+}
+
+func (c TreeConfig) fading() bool {
+	return c.FadingFactor > 0 && c.FadingFactor < 1
+}
+
+// defaultDriftAlpha is the leaf drift detector significance level used when
+// DriftAlpha is unset.
+const defaultDriftAlpha = 0.002
+
+// leafDriftWindow is the number of recent labels a leaf drift detector keeps.
+const leafDriftWindow = 1000
+
+// newDriftDetector returns a leaf drift detector, or nil if drift detection
+// is disabled. Leaf detectors are tested on every sample for the lifetime of
+// the leaf, so they use the bounded detector with the per-cut bound.
+func (c TreeConfig) newDriftDetector() *DriftDetector {
+	if !c.UseDriftDetection {
+		return nil
+	}
+	alpha := c.DriftAlpha
+	if alpha <= 0 {
+		alpha = defaultDriftAlpha
+	}
+	return NewBoundedADWIN(alpha, leafDriftWindow)
 }
 
 // Tree is an online Hoeffding decision tree used as a base learner
@@ -19,12 +111,22 @@ type Tree struct {
 	Config      TreeConfig
 	NumFeatures int
 	NodeCount   int
+
+	// Samples is the number of samples offered to the tree, including those
+	// with zero weight. It is the clock used by FadingFactor.
+	Samples uint64
+
+	// Rand is the tree's own random stream, seeded from Config.Seed. All
+	// randomness affecting the tree must be drawn from it so that training
+	// is reproducible.
+	Rand *RNG
 }
 
 func NewTree(cfg TreeConfig, numFeatures int) *Tree {
 	return &Tree{
 		Config:      cfg,
 		NumFeatures: numFeatures,
+		Rand:        NewRNG(cfg.Seed),
 	}
 }
 
@@ -32,10 +134,13 @@ func (t *Tree) initRoot(fv features.FeatureVector) {
 	bootstrap := make(features.FeatureVector, len(fv))
 	copy(bootstrap, fv)
 
-	t.Root = NewLeaf(0, t.NumFeatures, bootstrap)
-	if t.Config.UseDriftDetection {
-		t.Root.DriftDetector = NewADWIN(t.Config.DriftAlpha)
+	if t.Config.FeatureSubset != nil {
+		t.Root = newLeafFor(0, t.Config.FeatureSubset, bootstrap)
+	} else {
+		t.Root = NewLeaf(0, t.NumFeatures, bootstrap)
 	}
+	t.Root.LastUpdate = t.Samples
+	t.Root.DriftDetector = t.Config.newDriftDetector()
 	t.NodeCount = 1
 }
 
@@ -49,11 +154,21 @@ func (t *Tree) Predict(fv features.FeatureVector) float64 {
 	for !node.IsLeaf {
 		node = node.ChooseChild(fv)
 	}
-	return node.Predict(fv)
+	return node.Predict(fv, t.Config)
 }
 
 // Update performs an online update of the tree with a single sample.
 func (t *Tree) Update(fv features.FeatureVector, label bool) {
+	t.UpdateWeighted(fv, label, 1)
+}
+
+// UpdateWeighted performs an online update with a sample of the given
+// weight. Non-positive weights are ignored.
+func (t *Tree) UpdateWeighted(fv features.FeatureVector, label bool, weight float64) {
+	t.Samples++
+	if weight <= 0 {
+		return
+	}
 
 	if t.Root == nil {
 		t.initRoot(fv)
@@ -62,6 +177,7 @@ func (t *Tree) Update(fv features.FeatureVector, label bool) {
 	t.Root.Update(
 		features.FeatureVector(fv),
 		label,
-		t.Config,
+		weight,
+		t,
 	)
 }
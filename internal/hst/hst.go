@@ -0,0 +1,198 @@
+// Package hst implements Streaming Half-Space Trees (Tan, Ting & Liu, 2011)
+// for unsupervised anomaly detection.
+//
+// Each tree is a complete binary tree over a randomly perturbed workspace;
+// every internal node halves the workspace along a random feature. Nodes
+// keep two mass profiles: the latest window, which counts the samples of the
+// window being filled, and the reference window, which holds the counts of
+// the last complete window and is used for scoring. Samples falling into
+// regions that held little mass in the reference window get low mass scores.
+package hst
+
+import (
+	"math"
+
+	"github.com/kudmo/onlinerf/api/features"
+	"github.com/kudmo/onlinerf/internal/forest"
+)
+
+// Config controls a Forest of half-space trees.
+type Config struct {
+	NumTrees    int
+	NumFeatures int
+
+	// Height is the depth of every tree.
+	Height int
+
+	// WindowSize is the number of samples per mass-profile window.
+	WindowSize int
+
+	// SizeLimit stops scoring descent at nodes whose reference mass is at
+	// most this value.
+	SizeLimit float64
+
+	// Lower and Upper give the expected range of every feature.
+	Lower []float64
+	Upper []float64
+}
+
+type node struct {
+	dim   int
+	split float64
+	// ref and latest are the reference and latest mass profiles.
+	ref    float64
+	latest float64
+}
+
+// tree stores a complete binary tree in heap order: the children of node i
+// are 2i+1 and 2i+2.
+type tree struct {
+	nodes  []node
+	height int
+}
+
+func newTree(cfg Config, rng *forest.RNG) *tree {
+	t := &tree{
+		nodes:  make([]node, 1<<(cfg.Height+1)-1),
+		height: cfg.Height,
+	}
+
+	// Random workspace: a box around a random point that still covers the
+	// whole feature range.
+	lo := make([]float64, cfg.NumFeatures)
+	hi := make([]float64, cfg.NumFeatures)
+	for q := range lo {
+		s := cfg.Lower[q] + rng.Float64()*(cfg.Upper[q]-cfg.Lower[q])
+		r := 2 * math.Max(s-cfg.Lower[q], cfg.Upper[q]-s)
+		lo[q], hi[q] = s-r, s+r
+	}
+	t.build(0, 0, lo, hi, rng)
+	return t
+}
+
+func (t *tree) build(i, depth int, lo, hi []float64, rng *forest.RNG) {
+	if depth == t.height {
+		return
+	}
+	q := rng.Intn(len(lo))
+	split := (lo[q] + hi[q]) / 2
+	t.nodes[i].dim = q
+	t.nodes[i].split = split
+
+	saved := hi[q]
+	hi[q] = split
+	t.build(2*i+1, depth+1, lo, hi, rng)
+	hi[q] = saved
+
+	saved = lo[q]
+	lo[q] = split
+	t.build(2*i+2, depth+1, lo, hi, rng)
+	lo[q] = saved
+}
+
+func (t *tree) child(i int, fv features.FeatureVector) int {
+	n := &t.nodes[i]
+	if n.dim < len(fv) && fv[n.dim] >= n.split {
+		return 2*i + 2
+	}
+	return 2*i + 1
+}
+
+func (t *tree) update(fv features.FeatureVector) {
+	i := 0
+	for depth := 0; ; depth++ {
+		t.nodes[i].latest++
+		if depth == t.height {
+			return
+		}
+		i = t.child(i, fv)
+	}
+}
+
+// mass returns the mass score ref·2^depth of the deepest node on fv's path
+// whose reference mass exceeds sizeLimit.
+func (t *tree) mass(fv features.FeatureVector, sizeLimit float64) float64 {
+	i := 0
+	for depth := 0; ; depth++ {
+		n := &t.nodes[i]
+		if depth == t.height || n.ref <= sizeLimit {
+			return n.ref * math.Exp2(float64(depth))
+		}
+		i = t.child(i, fv)
+	}
+}
+
+func (t *tree) rotate() {
+	for i := range t.nodes {
+		t.nodes[i].ref = t.nodes[i].latest
+		t.nodes[i].latest = 0
+	}
+}
+
+// Forest is an ensemble of half-space trees. It is not safe for concurrent
+// use.
+type Forest struct {
+	cfg     Config
+	trees   []*tree
+	samples int
+	windows int
+}
+
+// New creates a forest drawing its random structure from rng. cfg must have
+// at least one feature and a range for every feature.
+func New(cfg Config, rng *forest.RNG) *Forest {
+	f := &Forest{cfg: cfg, trees: make([]*tree, cfg.NumTrees)}
+	for i := range f.trees {
+		f.trees[i] = newTree(cfg, rng)
+	}
+	return f
+}
+
+// Update adds a sample to the latest window. When the window is full it
+// becomes the reference window.
+func (f *Forest) Update(fv features.FeatureVector) {
+	for _, t := range f.trees {
+		t.update(fv)
+	}
+	f.samples++
+	if f.samples%f.cfg.WindowSize == 0 {
+		for _, t := range f.trees {
+			t.rotate()
+		}
+		f.windows++
+	}
+}
+
+// Ready reports whether a reference window is available for scoring.
+func (f *Forest) Ready() bool {
+	return f.windows > 0
+}
+
+// Samples returns the number of samples seen.
+func (f *Forest) Samples() int {
+	return f.samples
+}
+
+// Mass returns the mass score of fv averaged over trees and divided by the
+// window size. Values around 1 are typical for samples from a uniform
+// density; lower values indicate sparser, more anomalous regions.
+func (f *Forest) Mass(fv features.FeatureVector) float64 {
+	var sum float64
+	for _, t := range f.trees {
+		sum += t.mass(fv, f.cfg.SizeLimit)
+	}
+	return sum / float64(len(f.trees)) / float64(f.cfg.WindowSize)
+}
+
+// Score returns an anomaly score in [0,1]: per tree, 1 - min(1, mass /
+// WindowSize), averaged over trees. Samples in regions at least as dense as
+// a uniform distribution over the workspace score 0; samples in regions
+// that were empty in the reference window score 1.
+func (f *Forest) Score(fv features.FeatureVector) float64 {
+	w := float64(f.cfg.WindowSize)
+	var sum float64
+	for _, t := range f.trees {
+		sum += 1 - math.Min(1, t.mass(fv, f.cfg.SizeLimit)/w)
+	}
+	return sum / float64(len(f.trees))
+}
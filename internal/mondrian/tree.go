@@ -0,0 +1,290 @@
+// Package mondrian implements online Mondrian trees for binary
+// classification (Lakshminarayanan et al., 2014).
+//
+// A Mondrian tree partitions the bounding box of the data it has seen with
+// axis-aligned cuts placed at random times. New samples extend the tree
+// consistently: a sample outside a node's box may introduce a new split
+// above that node, so trees grow from the very first samples instead of
+// waiting for statistical evidence as Hoeffding trees do.
+package mondrian
+
+import (
+	"math"
+
+	"github.com/kudmo/onlinerf/api/features"
+	"github.com/kudmo/onlinerf/internal/forest"
+)
+
+// Config controls a single Mondrian tree.
+type Config struct {
+	// Lifetime is the budget λ of the Mondrian process. Splits are only
+	// created at times below Lifetime; +Inf grows the tree without limit
+	// (subject to leaf pausing and MaxNodes).
+	Lifetime float64
+
+	// Smoothing is the strength β of the hierarchical prior: a node's
+	// posterior is (pos + β·parent) / (n + β), so nodes with few samples
+	// borrow strength from their ancestors.
+	Smoothing float64
+
+	// MaxNodes bounds the number of nodes. Once reached, samples only
+	// extend the boxes and counts of existing nodes. Zero means unlimited.
+	MaxNodes int
+}
+
+// node is a block of the Mondrian partition.
+type node struct {
+	// lower and upper bound the samples seen in the node's subtree.
+	lower, upper []float64
+
+	// tau is the split time; leaves have tau = Lifetime.
+	tau float64
+
+	// dim and loc define the split of an internal node: samples with
+	// x[dim] <= loc go left.
+	dim   int
+	loc   float64
+	left  *node
+	right *node
+
+	// pos and neg count the labels of samples in the node's subtree.
+	pos, neg float64
+}
+
+func (n *node) isLeaf() bool {
+	return n.left == nil
+}
+
+// paused reports whether a leaf should not split for a sample with the
+// given label: all its samples, and the new one, share the same label.
+func (n *node) paused(label bool) bool {
+	if label {
+		return n.neg == 0
+	}
+	return n.pos == 0
+}
+
+func (n *node) count(label bool) {
+	if label {
+		n.pos++
+	} else {
+		n.neg++
+	}
+}
+
+func (n *node) extendBox(x features.FeatureVector) {
+	for d, v := range x {
+		n.lower[d] = math.Min(n.lower[d], v)
+		n.upper[d] = math.Max(n.upper[d], v)
+	}
+}
+
+// extension returns, per dimension, how far x lies outside the node's box,
+// and their sum (the rate of the exponential split-time distribution).
+func (n *node) extension(x features.FeatureVector) (ext []float64, rate float64) {
+	ext = make([]float64, len(x))
+	for d, v := range x {
+		e := math.Max(n.lower[d]-v, 0) + math.Max(v-n.upper[d], 0)
+		ext[d] = e
+		rate += e
+	}
+	return ext, rate
+}
+
+// Tree is an online Mondrian tree.
+type Tree struct {
+	root  *node
+	cfg   Config
+	rand  *forest.RNG
+	nodes int
+}
+
+// NewTree creates an empty tree whose randomness is drawn from the given
+// seed.
+func NewTree(cfg Config, seed uint64) *Tree {
+	if cfg.Lifetime <= 0 {
+		cfg.Lifetime = math.Inf(1)
+	}
+	return &Tree{cfg: cfg, rand: forest.NewRNG(seed)}
+}
+
+// NodeCount returns the number of nodes in the tree.
+func (t *Tree) NodeCount() int {
+	return t.nodes
+}
+
+func (t *Tree) newLeaf(x features.FeatureVector, label bool) *node {
+	n := &node{
+		lower: append([]float64(nil), x...),
+		upper: append([]float64(nil), x...),
+		tau:   t.cfg.Lifetime,
+	}
+	n.count(label)
+	t.nodes++
+	return n
+}
+
+// Update extends the tree with a labeled sample.
+func (t *Tree) Update(x features.FeatureVector, label bool) {
+	if t.root == nil {
+		t.root = t.newLeaf(x, label)
+		return
+	}
+	t.root = t.extend(t.root, 0, x, label)
+}
+
+// extend adds the sample to the subtree rooted at n, whose parent split at
+// parentTau, and returns the node that takes n's place: either n itself or
+// a new parent separating the sample from n.
+func (t *Tree) extend(n *node, parentTau float64, x features.FeatureVector, label bool) *node {
+	ext, rate := n.extension(x)
+	canSplit := t.cfg.MaxNodes <= 0 || t.nodes+2 <= t.cfg.MaxNodes
+	if rate > 0 && canSplit && !(n.isLeaf() && n.paused(label)) {
+		e := -math.Log(1-t.rand.Float64()) / rate
+		if parentTau+e < n.tau {
+			return t.splitAbove(n, parentTau+e, ext, rate, x, label)
+		}
+	}
+
+	// A paused leaf that receives the other label resumes splitting.
+	resume := n.isLeaf() && (n.pos == 0 || n.neg == 0) && !n.paused(label)
+	n.extendBox(x)
+	n.count(label)
+	if resume && canSplit {
+		t.splitBlock(n, parentTau)
+	}
+	if !n.isLeaf() {
+		if x[n.dim] <= n.loc {
+			n.left = t.extend(n.left, n.tau, x, label)
+		} else {
+			n.right = t.extend(n.right, n.tau, x, label)
+		}
+	}
+	return n
+}
+
+// splitAbove inserts a new parent of n at time tau whose split separates x
+// from n's box, with a new leaf holding x as the other child.
+func (t *Tree) splitAbove(n *node, tau float64, ext []float64, rate float64, x features.FeatureVector, label bool) *node {
+	// Pick the split dimension proportionally to the extension along it.
+	dim := len(ext) - 1
+	u := t.rand.Float64() * rate
+	for d, e := range ext {
+		if u < e {
+			dim = d
+			break
+		}
+		u -= e
+	}
+
+	parent := &node{
+		lower: append([]float64(nil), n.lower...),
+		upper: append([]float64(nil), n.upper...),
+		tau:   tau,
+		dim:   dim,
+		pos:   n.pos,
+		neg:   n.neg,
+	}
+	parent.extendBox(x)
+	parent.count(label)
+	t.nodes++
+
+	leaf := t.newLeaf(x, label)
+	if v := x[dim]; v > n.upper[dim] {
+		parent.loc = n.upper[dim] + t.rand.Float64()*(v-n.upper[dim])
+		parent.left, parent.right = n, leaf
+	} else {
+		parent.loc = v + t.rand.Float64()*(n.lower[dim]-v)
+		parent.left, parent.right = leaf, n
+	}
+	return parent
+}
+
+// splitBlock samples a split of leaf n inside its box, as the Mondrian
+// process would have done had the leaf not been paused. Samples are not
+// stored, so the children cover the two halves of the box and start without
+// counts; until they see samples they predict n's posterior through the
+// hierarchical prior.
+func (t *Tree) splitBlock(n *node, parentTau float64) {
+	var rate float64
+	for d := range n.lower {
+		rate += n.upper[d] - n.lower[d]
+	}
+	if rate == 0 {
+		return
+	}
+	tau := parentTau - math.Log(1-t.rand.Float64())/rate
+	if tau >= t.cfg.Lifetime {
+		return
+	}
+
+	// Pick the split dimension proportionally to the box side along it.
+	dim := len(n.lower) - 1
+	u := t.rand.Float64() * rate
+	for d := range n.lower {
+		side := n.upper[d] - n.lower[d]
+		if u < side {
+			dim = d
+			break
+		}
+		u -= side
+	}
+
+	n.tau = tau
+	n.dim = dim
+	n.loc = n.lower[dim] + t.rand.Float64()*(n.upper[dim]-n.lower[dim])
+	n.left = &node{
+		lower: append([]float64(nil), n.lower...),
+		upper: append([]float64(nil), n.upper...),
+		tau:   t.cfg.Lifetime,
+	}
+	n.right = &node{
+		lower: append([]float64(nil), n.lower...),
+		upper: append([]float64(nil), n.upper...),
+		tau:   t.cfg.Lifetime,
+	}
+	n.left.upper[dim] = n.loc
+	n.right.lower[dim] = n.loc
+	t.nodes += 2
+}
+
+// Predict returns the probability of the positive class and the probability
+// that x falls into a region the tree has not yet partitioned.
+//
+// Walking from the root, x may be separated from a node's box by a split the
+// tree has not sampled yet; in that case it would land in a new leaf whose
+// posterior equals the smoothed posterior of the node's parent. Predictions
+// far from the training data therefore fall back to ancestors and, at the
+// extreme, to the prior 0.5. The returned unseen probability measures how
+// much of the prediction came from such fallbacks.
+func (t *Tree) Predict(x features.FeatureVector) (prob, unseen float64) {
+	if t.root == nil {
+		return 0.5, 1
+	}
+
+	parentPost := 0.5
+	parentTau := 0.0
+	notSeparated := 1.0
+	for n := t.root; ; {
+		_, rate := n.extension(x)
+		if rate > 0 {
+			ps := 1 - math.Exp(-(n.tau-parentTau)*rate)
+			prob += notSeparated * ps * parentPost
+			unseen += notSeparated * ps
+			notSeparated *= 1 - ps
+		}
+
+		post := (n.pos + t.cfg.Smoothing*parentPost) / (n.pos + n.neg + t.cfg.Smoothing)
+		if n.isLeaf() || notSeparated == 0 {
+			prob += notSeparated * post
+			return prob, unseen
+		}
+
+		parentPost, parentTau = post, n.tau
+		if x[n.dim] <= n.loc {
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+}
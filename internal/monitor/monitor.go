@@ -0,0 +1,279 @@
+// Package monitor tracks per-feature input distributions and detects
+// covariate drift independently of labels.
+package monitor
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// Method selects the statistic used to compare feature distributions.
+type Method int
+
+const (
+	// PSI computes the population stability index between the reference
+	// window and the recent window over reference-quantile bins.
+	PSI Method = iota
+	// KS computes the two-sample Kolmogorov–Smirnov statistic between the
+	// reference window and the recent window.
+	KS
+	// PageHinkley runs a two-sided Page-Hinkley test on each feature's mean.
+	PageHinkley
+)
+
+// Config configures a Monitor.
+type Config struct {
+	Method Method
+
+	// ReferenceSize is the number of initial samples forming the reference
+	// distribution (PSI and KS).
+	ReferenceSize int
+	// WindowSize is the size of the sliding window of recent samples
+	// compared against the reference (PSI and KS).
+	WindowSize int
+	// Bins is the number of quantile bins used by PSI.
+	Bins int
+	// PSIThreshold is the PSI value above which a feature is reported as
+	// drifted.
+	PSIThreshold float64
+	// Alpha is the significance level of the KS test.
+	Alpha float64
+	// Delta is the magnitude of changes tolerated by Page-Hinkley.
+	Delta float64
+	// Lambda is the Page-Hinkley detection threshold.
+	Lambda float64
+}
+
+// Result is the drift status of a single feature.
+type Result struct {
+	// Score is the value of the configured statistic.
+	Score float64
+	// Threshold is the value Score is compared against.
+	Threshold float64
+	// Drifted reports whether Score exceeds Threshold.
+	Drifted bool
+	// Ready reports whether enough samples were seen to compute Score.
+	Ready bool
+}
+
+// psiEps keeps empty bins from producing infinite PSI values.
+const psiEps = 1e-4
+
+// Monitor tracks the distribution of every feature of a fixed-length
+// vector. It is safe for concurrent use.
+type Monitor struct {
+	mu       sync.Mutex
+	cfg      Config
+	features []*feature
+	samples  int64
+}
+
+type feature struct {
+	ref    []float64
+	sorted bool
+	recent []float64
+	next   int
+	ph     pageHinkley
+}
+
+// New creates a monitor for vectors with numFeatures entries.
+func New(cfg Config, numFeatures int) *Monitor {
+	m := &Monitor{cfg: cfg, features: make([]*feature, numFeatures)}
+	for i := range m.features {
+		m.features[i] = m.newFeature()
+	}
+	return m
+}
+
+func (m *Monitor) newFeature() *feature {
+	return &feature{
+		ref:    make([]float64, 0, m.cfg.ReferenceSize),
+		recent: make([]float64, 0, m.cfg.WindowSize),
+		ph:     pageHinkley{delta: m.cfg.Delta},
+	}
+}
+
+// Add records one input vector. Entries beyond the monitored length are
+// ignored.
+func (m *Monitor) Add(fv []float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.samples++
+	for i, f := range m.features {
+		if i >= len(fv) {
+			break
+		}
+		f.add(fv[i])
+	}
+}
+
+func (f *feature) add(x float64) {
+	f.ph.add(x)
+	if len(f.ref) < cap(f.ref) {
+		f.ref = append(f.ref, x)
+		return
+	}
+	if !f.sorted {
+		sort.Float64s(f.ref)
+		f.sorted = true
+	}
+	if len(f.recent) < cap(f.recent) {
+		f.recent = append(f.recent, x)
+		return
+	}
+	f.recent[f.next] = x
+	f.next = (f.next + 1) % len(f.recent)
+}
+
+// Samples returns the number of vectors seen since creation.
+func (m *Monitor) Samples() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.samples
+}
+
+// Results returns the current drift status of every feature.
+func (m *Monitor) Results() []Result {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]Result, len(m.features))
+	for i, f := range m.features {
+		out[i] = m.result(f)
+	}
+	return out
+}
+
+func (m *Monitor) result(f *feature) Result {
+	var r Result
+	switch m.cfg.Method {
+	case PageHinkley:
+		r.Ready = f.ph.n > 0
+		r.Score = f.ph.statistic()
+		r.Threshold = m.cfg.Lambda
+	case KS:
+		r.Ready = f.sorted && len(f.recent) == cap(f.recent)
+		if !r.Ready {
+			return r
+		}
+		recent := sortedCopy(f.recent)
+		r.Score = ksStatistic(f.ref, recent)
+		n, k := float64(len(f.ref)), float64(len(recent))
+		c := math.Sqrt(-math.Log(m.cfg.Alpha/2) / 2)
+		r.Threshold = c * math.Sqrt((n+k)/(n*k))
+	default:
+		r.Ready = f.sorted && len(f.recent) == cap(f.recent)
+		if !r.Ready {
+			return r
+		}
+		r.Score = psi(f.ref, f.recent, m.cfg.Bins)
+		r.Threshold = m.cfg.PSIThreshold
+	}
+	r.Drifted = r.Ready && r.Score > r.Threshold
+	return r
+}
+
+// Rebase makes the recent window the new reference distribution and
+// restarts the Page-Hinkley tests, e.g. after a drift has been handled.
+// The window keeps its samples, so results stay ready and scores restart
+// from zero as new samples replace them. Features whose window is not full
+// yet keep their reference.
+func (m *Monitor) Rebase() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, f := range m.features {
+		f.ph = pageHinkley{delta: m.cfg.Delta}
+		if len(f.recent) < cap(f.recent) {
+			continue
+		}
+		f.ref = sortedCopy(f.recent)
+		f.sorted = true
+	}
+}
+
+func sortedCopy(xs []float64) []float64 {
+	out := append([]float64(nil), xs...)
+	sort.Float64s(out)
+	return out
+}
+
+// ksStatistic returns the maximum distance between the empirical CDFs of
+// two sorted samples.
+func ksStatistic(a, b []float64) float64 {
+	var i, j int
+	var d float64
+	for i < len(a) && j < len(b) {
+		x := math.Min(a[i], b[j])
+		for i < len(a) && a[i] <= x {
+			i++
+		}
+		for j < len(b) && b[j] <= x {
+			j++
+		}
+		diff := math.Abs(float64(i)/float64(len(a)) - float64(j)/float64(len(b)))
+		if diff > d {
+			d = diff
+		}
+	}
+	return d
+}
+
+// psi returns the population stability index of recent against the sorted
+// reference sample, using bins delimited by reference quantiles.
+func psi(ref, recent []float64, bins int) float64 {
+	edges := make([]float64, 0, bins-1)
+	for b := 1; b < bins; b++ {
+		e := ref[b*len(ref)/bins]
+		if len(edges) == 0 || e > edges[len(edges)-1] {
+			edges = append(edges, e)
+		}
+	}
+
+	expected := histogram(ref, edges)
+	actual := histogram(recent, edges)
+
+	var s float64
+	for b := range expected {
+		e := math.Max(expected[b]/float64(len(ref)), psiEps)
+		a := math.Max(actual[b]/float64(len(recent)), psiEps)
+		s += (a - e) * math.Log(a/e)
+	}
+	return s
+}
+
+// histogram counts xs in the bins (-inf, e0), [e0, e1), ..., [ek, +inf).
+func histogram(xs, edges []float64) []float64 {
+	counts := make([]float64, len(edges)+1)
+	for _, x := range xs {
+		counts[sort.SearchFloat64s(edges, math.Nextafter(x, math.Inf(1)))]++
+	}
+	return counts
+}
+
+// pageHinkley is a two-sided Page-Hinkley test on the mean of a stream.
+type pageHinkley struct {
+	delta float64
+
+	n    float64
+	mean float64
+
+	up, upMin     float64
+	down, downMax float64
+}
+
+func (p *pageHinkley) add(x float64) {
+	p.n++
+	p.mean += (x - p.mean) / p.n
+	p.up += x - p.mean - p.delta
+	p.upMin = math.Min(p.upMin, p.up)
+	p.down += x - p.mean + p.delta
+	p.downMax = math.Max(p.downMax, p.down)
+}
+
+// statistic returns the larger of the upward and downward test statistics.
+func (p *pageHinkley) statistic() float64 {
+	return math.Max(p.up-p.upMin, p.downMax-p.down)
+}